@@ -7,7 +7,8 @@
 //
 // The statistics provided include totaler (with the Totaler interface), average
 // (with the Averager interface), and distributions (with the Bucketer
-// interface).
+// interface).  In addition, SlowestN tracks the individual samples with the
+// largest durations (e.g. the slowest requests) over a time window.
 //
 // Each statistic must have a unique name, "Name".  One or more statistics is
 // placed in a structure and registered, with a name, via a call to Register()
@@ -18,7 +19,9 @@ package bucketstats
 
 import (
 	"math/bits"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type StatStringFormat int
@@ -363,3 +366,60 @@ func (this *BucketLogRoot2Round) DistGet() []BucketInfo {
 func (this *BucketLogRoot2Round) Sprint(stringFmt StatStringFormat, pkgName string, statsGroupName string) string {
 	return bucketSprint(stringFmt, pkgName, statsGroupName, this.Name, this.DistGet())
 }
+
+// SlowestSample describes a single sample retained by a SlowestN statistic.
+//
+// Key identifies the operation (e.g. a volume name and inode number or an
+// object name), Duration is how long it took, and Time is when it was added.
+//
+type SlowestSample struct {
+	Key      string
+	Duration time.Duration
+	Time     time.Time
+}
+
+// SlowestN holds the N samples with the largest Duration added during the
+// current window.  It does not support the Totaler interface since each sample
+// is identified by a key rather than simply being a value.
+//
+// N determines the number of samples retained.  If N is not set it defaults to
+// 10.  Window determines how long samples are retained.  When Window has elapsed
+// since the current window started, all retained samples are discarded and a new
+// window is started.  If Window is not set it defaults to one minute.  Both must
+// be set before the statistic is registered (or, if it is never registered,
+// first used) and cannot be changed afterward.
+//
+// Name must be unique within statistics in the structure.  If it is "" then
+// Register() will assign a name based on the name of the field.
+//
+type SlowestN struct {
+	Name        string
+	N           uint
+	Window      time.Duration
+	sync.Mutex                    // Protects windowStart and samples
+	windowStart time.Time         //
+	samples     slowestSampleHeap // Min-heap on Duration, so samples[0] is the fastest retained sample
+	now         func() time.Time  // Returns time.Now() unless overridden for testing
+}
+
+// Add a sample identified by key that took duration.
+//
+// If N samples with a Duration at least as large have already been added during
+// the current window, the sample is discarded.
+//
+func (this *SlowestN) Add(key string, duration time.Duration) {
+	this.add(key, duration)
+}
+
+// Return the samples retained for the current window ordered from slowest to
+// fastest.
+//
+func (this *SlowestN) SlowestGet() []SlowestSample {
+	return this.slowestGet()
+}
+
+// Return a string with the statistic's value in the specified format.
+//
+func (this *SlowestN) Sprint(stringFmt StatStringFormat, pkgName string, statsGroupName string) string {
+	return this.sprint(stringFmt, pkgName, statsGroupName)
+}
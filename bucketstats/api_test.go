@@ -7,7 +7,11 @@ import (
 	"fmt"
 	"math/rand"
 	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // a structure containing all of the bucketstats statistics types and other
@@ -548,6 +552,159 @@ func TestSprintStats(t *testing.T) {
 	}
 }
 
+type slowestStats struct {
+	Slowest SlowestN
+}
+
+func TestSlowestN(t *testing.T) {
+
+	const (
+		nSlowest = 5
+		nSamples = 1000
+	)
+
+	var (
+		durations []time.Duration
+		fakeNow   time.Time
+		samples   []SlowestSample
+	)
+
+	myStats := slowestStats{Slowest: SlowestN{N: nSlowest, Window: time.Minute}}
+
+	// use a fake clock so the window can be advanced without sleeping
+	fakeNow = time.Now()
+	myStats.Slowest.now = func() time.Time { return fakeNow }
+
+	Register("main", "slowestStats", &myStats)
+	defer UnRegister("main", "slowestStats")
+
+	// feed the synthetic durations in random order; each key is unique
+	for _, i := range rand.Perm(nSamples) {
+		durations = append(durations, time.Duration(i)*time.Microsecond)
+		myStats.Slowest.Add(fmt.Sprintf("key%d", i), time.Duration(i)*time.Microsecond)
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] > durations[j] })
+
+	// the kept set must be exactly the top N, slowest first
+	samples = myStats.Slowest.SlowestGet()
+	if len(samples) != nSlowest {
+		t.Fatalf("SlowestGet() returned %d samples, expected %d", len(samples), nSlowest)
+	}
+	for i, sample := range samples {
+		if sample.Duration != durations[i] {
+			t.Errorf("SlowestGet()[%d].Duration is %v, expected %v", i, sample.Duration, durations[i])
+		}
+		if sample.Key != fmt.Sprintf("key%d", sample.Duration/time.Microsecond) {
+			t.Errorf("SlowestGet()[%d].Key is '%s', which doesn't match Duration %v", i, sample.Key, sample.Duration)
+		}
+		if !sample.Time.Equal(fakeNow) {
+			t.Errorf("SlowestGet()[%d].Time is %v, expected %v", i, sample.Time, fakeNow)
+		}
+	}
+
+	// a sample faster than all those retained is discarded
+	myStats.Slowest.Add("fast", time.Nanosecond)
+	for _, sample := range myStats.Slowest.SlowestGet() {
+		if sample.Key == "fast" {
+			t.Errorf("SlowestGet() retained a sample faster than the top N")
+		}
+	}
+
+	// the stat prints as "name count:N key:usecs ..."
+	statsString := SprintStats(StatFormatParsable1, "main", "slowestStats")
+	expected := fmt.Sprintf("main.slowestStats.Slowest count:%d key%d:%d ", nSlowest, nSamples-1, nSamples-1)
+	if !strings.HasPrefix(statsString, expected) {
+		t.Errorf("SprintStats() returned '%s', expected it to begin with '%s'", statsString, expected)
+	}
+
+	// moving past the window discards all retained samples
+	fakeNow = fakeNow.Add(time.Minute)
+	samples = myStats.Slowest.SlowestGet()
+	if len(samples) != 0 {
+		t.Fatalf("SlowestGet() returned %d samples after the window elapsed, expected 0", len(samples))
+	}
+
+	myStats.Slowest.Add("new", time.Nanosecond)
+	samples = myStats.Slowest.SlowestGet()
+	if len(samples) != 1 || samples[0].Key != "new" {
+		t.Errorf("SlowestGet() in new window returned %v, expected only sample 'new'", samples)
+	}
+}
+
+// Test that, like the other statistics, a SlowestN is usable without Register()
+func TestSlowestNUnregistered(t *testing.T) {
+
+	var (
+		samples []SlowestSample
+		slowest SlowestN
+	)
+
+	samples = slowest.SlowestGet()
+	if len(samples) != 0 {
+		t.Fatalf("SlowestGet() of an unused SlowestN returned %v, expected no samples", samples)
+	}
+
+	for i := 0; i < slowestNDefaultN+1; i++ {
+		slowest.Add(fmt.Sprintf("key%d", i), time.Duration(i))
+	}
+
+	if slowest.N != slowestNDefaultN || slowest.Window != slowestNDefaultWindow {
+		t.Errorf("unregistered SlowestN has N %d and Window %v, expected defaults %d and %v",
+			slowest.N, slowest.Window, slowestNDefaultN, slowestNDefaultWindow)
+	}
+
+	samples = slowest.SlowestGet()
+	if len(samples) != slowestNDefaultN || samples[0].Key != fmt.Sprintf("key%d", slowestNDefaultN) {
+		t.Errorf("SlowestGet() of an unregistered SlowestN returned %v", samples)
+	}
+}
+
+func TestSlowestNConcurrent(t *testing.T) {
+
+	const (
+		nSlowest      = 10
+		nGoroutines   = 8
+		nPerGoroutine = 1000
+	)
+
+	var (
+		wg sync.WaitGroup
+	)
+
+	myStats := slowestStats{Slowest: SlowestN{N: nSlowest}}
+
+	Register("main", "slowestStatsConcurrent", &myStats)
+	defer UnRegister("main", "slowestStatsConcurrent")
+
+	// each goroutine adds a disjoint set of durations, so the top N of the
+	// union is known in advance
+	for g := 0; g < nGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			for i := 0; i < nPerGoroutine; i++ {
+				myStats.Slowest.Add(fmt.Sprintf("g%d", g), time.Duration(i*nGoroutines+g))
+				if i%100 == 0 {
+					_ = myStats.Slowest.SlowestGet()
+				}
+			}
+			wg.Done()
+		}(g)
+	}
+	wg.Wait()
+
+	samples := myStats.Slowest.SlowestGet()
+	if len(samples) != nSlowest {
+		t.Fatalf("SlowestGet() returned %d samples, expected %d", len(samples), nSlowest)
+	}
+	for i, sample := range samples {
+		expected := time.Duration(nPerGoroutine*nGoroutines - 1 - i)
+		if sample.Duration != expected {
+			t.Errorf("SlowestGet()[%d].Duration is %v, expected %v", i, sample.Duration, expected)
+		}
+	}
+}
+
 // Invoke function aFunc, which is expected to panic.  If it does, return the
 // value returned by recover() as a string, otherwise return the empty string.
 //
@@ -7,12 +7,15 @@
 package bucketstats
 
 import (
+	"container/heap"
 	"fmt"
 	"math/big"
 	"math/bits"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 )
 
@@ -21,6 +24,16 @@ var (
 	statsNameMapLock   sync.Mutex
 )
 
+const (
+	slowestNDefaultN      = 10
+	slowestNDefaultWindow = time.Minute
+)
+
+// SlowestN contains a sync.Mutex, so avoid the copy reflect.TypeOf(SlowestN{})
+// would make (and go vet would complain about).
+//
+var slowestNType = reflect.TypeOf((*SlowestN)(nil)).Elem()
+
 // Register a set of statistics, where the statistics are one or more fields in
 // the passed structure.
 //
@@ -64,7 +77,8 @@ func register(pkgName string, statsGroupName string, statsStruct interface{}) {
 		if fieldAsType != reflect.TypeOf(countStat) &&
 			fieldAsType != reflect.TypeOf(averageStat) &&
 			fieldAsType != reflect.TypeOf(bucketLog2Stat) &&
-			fieldAsType != reflect.TypeOf(bucketLogRoot2Stat) {
+			fieldAsType != reflect.TypeOf(bucketLogRoot2Stat) &&
+			fieldAsType != slowestNType {
 			continue
 		}
 
@@ -109,6 +123,10 @@ func register(pkgName string, statsGroupName string, statsStruct interface{}) {
 			} else if v.NBucket < 17 {
 				v.NBucket = 17
 			}
+		case *SlowestN:
+			v.Lock()
+			v.applyDefaults()
+			v.Unlock()
 		default:
 			panic(fmt.Sprintf("statistics Group '%s' field %s type '%v' unknown: internal error",
 				statsGroupName, fieldName, fieldAsType))
@@ -225,7 +243,8 @@ func sprintStatsStruct(statFmt StatStringFormat, pkgName string, statsGroupName
 		if fieldAsType != reflect.TypeOf(countStat) &&
 			fieldAsType != reflect.TypeOf(averageStat) &&
 			fieldAsType != reflect.TypeOf(bucketLog2Stat) &&
-			fieldAsType != reflect.TypeOf(bucketLogRoot2Stat) {
+			fieldAsType != reflect.TypeOf(bucketLogRoot2Stat) &&
+			fieldAsType != slowestNType {
 			continue
 		}
 
@@ -238,6 +257,8 @@ func sprintStatsStruct(statFmt StatStringFormat, pkgName string, statsGroupName
 			statValues += v.Sprint(statFmt, pkgName, statsGroupName)
 		case *BucketLogRoot2Round:
 			statValues += v.Sprint(statFmt, pkgName, statsGroupName)
+		case *SlowestN:
+			statValues += v.Sprint(statFmt, pkgName, statsGroupName)
 		default:
 			panic(fmt.Sprintf("Unknown type in struct: %s", fieldAsType.Name()))
 		}
@@ -484,3 +505,115 @@ func scrubName(name string) string {
 
 	return strings.Map(replaceChar, name)
 }
+
+// slowestSampleHeap implements heap.Interface as a min-heap on Duration so that
+// the fastest of the retained samples is always the one to be evicted.
+//
+type slowestSampleHeap []SlowestSample
+
+func (h slowestSampleHeap) Len() int           { return len(h) }
+func (h slowestSampleHeap) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h slowestSampleHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *slowestSampleHeap) Push(x interface{}) {
+	*h = append(*h, x.(SlowestSample))
+}
+
+func (h *slowestSampleHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// Apply the defaults for N, Window, and now that were not set and start a new
+// window.  Called by Register() or, for a statistic that was never registered,
+// on first use.  Must be called with the lock held.
+//
+func (this *SlowestN) applyDefaults() {
+	if this.N == 0 {
+		this.N = slowestNDefaultN
+	}
+	if this.Window == 0 {
+		this.Window = slowestNDefaultWindow
+	}
+	if this.now == nil {
+		this.now = time.Now
+	}
+	this.windowStart = this.now()
+	this.samples = make(slowestSampleHeap, 0, this.N)
+}
+
+// Start a new window if the current one has elapsed.  Must be called with the
+// lock held.
+//
+func (this *SlowestN) checkWindow(now time.Time) {
+	if now.Sub(this.windowStart) >= this.Window {
+		this.windowStart = now
+		this.samples = this.samples[:0]
+	}
+}
+
+func (this *SlowestN) add(key string, duration time.Duration) {
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.now == nil {
+		this.applyDefaults()
+	}
+
+	now := this.now()
+	this.checkWindow(now)
+
+	if uint(len(this.samples)) < this.N {
+		heap.Push(&this.samples, SlowestSample{Key: key, Duration: duration, Time: now})
+		return
+	}
+
+	// replace the fastest retained sample if this one is slower
+	if duration > this.samples[0].Duration {
+		this.samples[0] = SlowestSample{Key: key, Duration: duration, Time: now}
+		heap.Fix(&this.samples, 0)
+	}
+}
+
+func (this *SlowestN) slowestGet() (samples []SlowestSample) {
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.now == nil {
+		this.applyDefaults()
+	}
+	this.checkWindow(this.now())
+
+	samples = make([]SlowestSample, len(this.samples))
+	copy(samples, this.samples)
+
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Duration > samples[j].Duration })
+
+	return
+}
+
+// Return a string with the statistic's value in the specified format.
+//
+// Each retained sample is printed as "key:usecs", slowest first.
+//
+func (this *SlowestN) sprint(statFmt StatStringFormat, pkgName string, statsGroupName string) string {
+
+	statName := statisticName(statFmt, pkgName, statsGroupName, this.Name)
+	samples := this.slowestGet()
+
+	switch statFmt {
+	case StatFormatParsable1:
+		line := fmt.Sprintf("%s count:%d", statName, len(samples))
+		for _, sample := range samples {
+			line += fmt.Sprintf(" %s:%d", scrubName(sample.Key), sample.Duration.Microseconds())
+		}
+		return line + "\n"
+	}
+
+	return fmt.Sprintf("statName '%s': Unknown StatStringFormat: '%v'\n", statName, statFmt)
+}
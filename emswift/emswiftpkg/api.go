@@ -4,6 +4,9 @@
 package emswiftpkg
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/NVIDIA/proxyfs/conf"
 )
 
@@ -21,3 +24,69 @@ func Stop() (err error) {
 	err = stop()
 	return
 }
+
+// RecordedRequest describes a single request handled by either the NoAuth or
+// Auth Swift Proxy while recording is enabled. Path is the request's path as
+// received (i.e. prior to any translation performed by the Auth Swift Proxy).
+// Only the headers listed in RecordedHeaderNames are captured.
+//
+type RecordedRequest struct {
+	Time          time.Time
+	Method        string
+	Path          string
+	Headers       http.Header
+	RequestBytes  uint64
+	ResponseBytes uint64
+	StatusCode    int
+}
+
+// RecordedHeaderNames lists the request headers captured in RecordedRequest.Headers
+//
+var RecordedHeaderNames = []string{"Range", "Content-Type", "Transfer-Encoding", "X-Auth-Token"}
+
+// EnableRecording is called to start recording requests. At most maxRequests
+// (but at least one) requests are retained, with the oldest discarded first. If recording was
+// already enabled, previously recorded requests are discarded. When recording
+// is disabled, only a single atomic load is added to each request.
+//
+func EnableRecording(maxRequests int) {
+	enableRecording(maxRequests)
+}
+
+// DisableRecording is called to stop recording requests and discard those
+// already recorded.
+//
+func DisableRecording() {
+	disableRecording()
+}
+
+// ResetRecording is called to discard previously recorded requests.
+//
+func ResetRecording() {
+	resetRecording()
+}
+
+// Requests returns the retained recorded requests, oldest first.
+//
+func Requests() (recordedRequests []RecordedRequest) {
+	recordedRequests = requests()
+	return
+}
+
+// CountMatching returns the number of retained recorded requests with the
+// specified method (or any method if method == "") and a Path matching pathRegexp.
+//
+func CountMatching(method string, pathRegexp string) (count int, err error) {
+	count, err = countMatching(method, pathRegexp)
+	return
+}
+
+// WaitForRequest is called to await a recorded request (including one already
+// recorded) with the specified method (or any method if method == "") and a Path
+// matching pathRegexp. If no such request is recorded before timeout elapses,
+// an error is returned.
+//
+func WaitForRequest(method string, pathRegexp string, timeout time.Duration) (recordedRequest RecordedRequest, err error) {
+	recordedRequest, err = waitForRequest(method, pathRegexp, timeout)
+	return
+}
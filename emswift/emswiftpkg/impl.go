@@ -366,12 +366,18 @@ func stopNoAuth() (err error) {
 func (dummy *authEmulatorStruct) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
 	var (
 		noAuthPath             string
+		recordRequestEnd       func()
 		xAuthKey               string
 		xAuthUser              string
 		xAuthUserSplit2OnColon []string
 		xStorageURL            string
 	)
 
+	responseWriter, request, recordRequestEnd = recordRequestBegin(responseWriter, request)
+	if nil != recordRequestEnd {
+		defer recordRequestEnd()
+	}
+
 	// Handle the GET of/on info & AuthURL cases
 
 	if http.MethodGet == request.Method {
@@ -512,6 +518,15 @@ func doAuthPROXYFS(responseWriter http.ResponseWriter, request *http.Request) {
 }
 
 func (dummy *noAuthEmulatorStruct) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	var (
+		recordRequestEnd func()
+	)
+
+	responseWriter, request, recordRequestEnd = recordRequestBegin(responseWriter, request)
+	if nil != recordRequestEnd {
+		defer recordRequestEnd()
+	}
+
 	switch request.Method {
 	case http.MethodDelete:
 		doNoAuthDELETE(responseWriter, request)
@@ -0,0 +1,206 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package emswiftpkg
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type recorderStruct struct {
+	sync.Mutex
+	enabled          uint32            // Accessed atomically; != 0 if recording is enabled
+	maxRequests      int               //
+	recordedRequests []RecordedRequest // Oldest first
+	changedChan      chan struct{}     // Closed (and replaced) each time a request is recorded
+}
+
+var recorder recorderStruct
+
+type recordingResponseWriterStruct struct {
+	http.ResponseWriter
+	statusCode    int
+	responseBytes uint64
+}
+
+type recordingRequestBodyStruct struct {
+	io.ReadCloser
+	requestBytes uint64
+}
+
+func enableRecording(maxRequests int) {
+	if maxRequests < 1 {
+		maxRequests = 1
+	}
+
+	recorder.Lock()
+	recorder.maxRequests = maxRequests
+	recorder.recordedRequests = make([]RecordedRequest, 0, maxRequests)
+	if nil == recorder.changedChan {
+		recorder.changedChan = make(chan struct{})
+	}
+	atomic.StoreUint32(&recorder.enabled, 1)
+	recorder.Unlock()
+}
+
+func disableRecording() {
+	recorder.Lock()
+	atomic.StoreUint32(&recorder.enabled, 0)
+	recorder.recordedRequests = nil
+	recorder.Unlock()
+}
+
+func resetRecording() {
+	recorder.Lock()
+	recorder.recordedRequests = make([]RecordedRequest, 0, recorder.maxRequests)
+	recorder.Unlock()
+}
+
+func requests() (recordedRequests []RecordedRequest) {
+	recorder.Lock()
+	recordedRequests = make([]RecordedRequest, len(recorder.recordedRequests))
+	copy(recordedRequests, recorder.recordedRequests)
+	recorder.Unlock()
+	return
+}
+
+func countMatching(method string, pathRegexp string) (count int, err error) {
+	var (
+		pathRE *regexp.Regexp
+	)
+
+	pathRE, err = regexp.Compile(pathRegexp)
+	if nil != err {
+		return
+	}
+
+	recorder.Lock()
+	for _, recordedRequest := range recorder.recordedRequests {
+		if recordedRequestMatches(&recordedRequest, method, pathRE) {
+			count++
+		}
+	}
+	recorder.Unlock()
+
+	return
+}
+
+func waitForRequest(method string, pathRegexp string, timeout time.Duration) (recordedRequest RecordedRequest, err error) {
+	var (
+		changedChan chan struct{}
+		pathRE      *regexp.Regexp
+		timer       *time.Timer
+	)
+
+	pathRE, err = regexp.Compile(pathRegexp)
+	if nil != err {
+		return
+	}
+
+	timer = time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		recorder.Lock()
+		if 0 == atomic.LoadUint32(&recorder.enabled) {
+			recorder.Unlock()
+			err = fmt.Errorf("recording is not enabled")
+			return
+		}
+		for _, recordedRequest = range recorder.recordedRequests {
+			if recordedRequestMatches(&recordedRequest, method, pathRE) {
+				recorder.Unlock()
+				err = nil
+				return
+			}
+		}
+		changedChan = recorder.changedChan
+		recorder.Unlock()
+
+		select {
+		case <-changedChan:
+		case <-timer.C:
+			recordedRequest = RecordedRequest{}
+			err = fmt.Errorf("no %s request matching \"%s\" recorded within %v", method, pathRegexp, timeout)
+			return
+		}
+	}
+}
+
+func recordedRequestMatches(recordedRequest *RecordedRequest, method string, pathRE *regexp.Regexp) bool {
+	return (("" == method) || (method == recordedRequest.Method)) && pathRE.MatchString(recordedRequest.Path)
+}
+
+// recordRequestBegin is called at the start of each ServeHTTP(). If recording is
+// enabled, wrapped versions of responseWriter and request are returned along with
+// a func to be called once the request has been handled. Otherwise, responseWriter
+// and request are returned unmodified along with a nil func.
+//
+func recordRequestBegin(responseWriter http.ResponseWriter, request *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	var (
+		recordedRequest         RecordedRequest
+		recordingRequestBody    *recordingRequestBodyStruct
+		recordingResponseWriter *recordingResponseWriterStruct
+	)
+
+	if 0 == atomic.LoadUint32(&recorder.enabled) {
+		return responseWriter, request, nil
+	}
+
+	recordedRequest = RecordedRequest{
+		Time:    time.Now(),
+		Method:  request.Method,
+		Path:    request.URL.Path,
+		Headers: make(http.Header),
+	}
+
+	for _, headerName := range RecordedHeaderNames {
+		if headerValues, ok := request.Header[headerName]; ok {
+			recordedRequest.Headers[headerName] = append([]string(nil), headerValues...)
+		}
+	}
+
+	recordingResponseWriter = &recordingResponseWriterStruct{ResponseWriter: responseWriter, statusCode: http.StatusOK}
+	recordingRequestBody = &recordingRequestBodyStruct{ReadCloser: request.Body}
+	request.Body = recordingRequestBody
+
+	return recordingResponseWriter, request, func() {
+		recordedRequest.RequestBytes = recordingRequestBody.requestBytes
+		recordedRequest.ResponseBytes = recordingResponseWriter.responseBytes
+		recordedRequest.StatusCode = recordingResponseWriter.statusCode
+
+		recorder.Lock()
+		if 0 != atomic.LoadUint32(&recorder.enabled) {
+			if len(recorder.recordedRequests) >= recorder.maxRequests {
+				recorder.recordedRequests = append(recorder.recordedRequests[:0], recorder.recordedRequests[1:]...)
+			}
+			recorder.recordedRequests = append(recorder.recordedRequests, recordedRequest)
+			close(recorder.changedChan)
+			recorder.changedChan = make(chan struct{})
+		}
+		recorder.Unlock()
+	}
+}
+
+func (recordingResponseWriter *recordingResponseWriterStruct) WriteHeader(statusCode int) {
+	recordingResponseWriter.statusCode = statusCode
+	recordingResponseWriter.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (recordingResponseWriter *recordingResponseWriterStruct) Write(buf []byte) (n int, err error) {
+	n, err = recordingResponseWriter.ResponseWriter.Write(buf)
+	recordingResponseWriter.responseBytes += uint64(n)
+	return
+}
+
+func (recordingRequestBody *recordingRequestBodyStruct) Read(buf []byte) (n int, err error) {
+	n, err = recordingRequestBody.ReadCloser.Read(buf)
+	recordingRequestBody.requestBytes += uint64(n)
+	return
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package emswiftpkg
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/proxyfs/conf"
+)
+
+func TestRecorder(t *testing.T) {
+	var (
+		confMap     conf.ConfMap
+		confStrings = []string{
+			"EMSWIFT.NoAuthIPAddr=127.0.0.1",
+			"EMSWIFT.NoAuthTCPPort=9999",
+			"EMSWIFT.MaxAccountNameLength=256",
+			"EMSWIFT.MaxContainerNameLength=256",
+			"EMSWIFT.MaxObjectNameLength=1024",
+			"EMSWIFT.AccountListingLimit=10000",
+			"EMSWIFT.ContainerListingLimit=10000",
+		}
+		count            int
+		err              error
+		recordedRequest  RecordedRequest
+		recordedRequests []RecordedRequest
+		urlPrefix        string
+	)
+
+	confMap, err = conf.MakeConfMapFromStrings(confStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings(confStrings) returned unexpected error: %v", err)
+	}
+
+	err = Start(confMap)
+	if nil != err {
+		t.Fatalf("Start(confMap) returned unexpected error: %v", err)
+	}
+
+	urlPrefix = "http://" + globals.noAuthEmulator.httpServer.Addr + "/v1/"
+
+	// Requests prior to enabling recording are not recorded
+
+	testRecorderDo(t, http.MethodPut, urlPrefix+"TestAccount", nil, nil, http.StatusCreated)
+
+	EnableRecording(4)
+
+	testRecorderDo(t, http.MethodPut, urlPrefix+"TestAccount/TestContainer", nil, nil, http.StatusCreated)
+	testRecorderDo(t, http.MethodPut, urlPrefix+"TestAccount/TestContainer/FooObject", []byte("12345678"), nil, http.StatusCreated)
+	testRecorderDo(t, http.MethodGet, urlPrefix+"TestAccount/TestContainer/FooObject", nil, map[string]string{"Range": "bytes=2-5"}, http.StatusPartialContent)
+	testRecorderDo(t, http.MethodGet, urlPrefix+"TestAccount/TestContainer/BarObject", nil, nil, http.StatusNotFound)
+
+	recordedRequests = Requests()
+	if 4 != len(recordedRequests) {
+		t.Fatalf("Requests() returned %d requests, expected 4", len(recordedRequests))
+	}
+
+	if (http.MethodPut != recordedRequests[1].Method) || ("/v1/TestAccount/TestContainer/FooObject" != recordedRequests[1].Path) || (8 != recordedRequests[1].RequestBytes) || (http.StatusCreated != recordedRequests[1].StatusCode) {
+		t.Fatalf("Requests()[1] unexpected: %+v", recordedRequests[1])
+	}
+	if ("bytes=2-5" != recordedRequests[2].Headers.Get("Range")) || (4 != recordedRequests[2].ResponseBytes) || (http.StatusPartialContent != recordedRequests[2].StatusCode) {
+		t.Fatalf("Requests()[2] unexpected: %+v", recordedRequests[2])
+	}
+	if http.StatusNotFound != recordedRequests[3].StatusCode {
+		t.Fatalf("Requests()[3] unexpected: %+v", recordedRequests[3])
+	}
+
+	count, err = CountMatching(http.MethodGet, "^/v1/TestAccount/TestContainer/.*Object$")
+	if (nil != err) || (2 != count) {
+		t.Fatalf("CountMatching(GET,) returned count: %d err: %v", count, err)
+	}
+	count, err = CountMatching("", "FooObject$")
+	if (nil != err) || (2 != count) {
+		t.Fatalf("CountMatching(\"\",) returned count: %d err: %v", count, err)
+	}
+	_, err = CountMatching("", "(")
+	if nil == err {
+		t.Fatalf("CountMatching() with bad pathRegexp should have failed")
+	}
+
+	// Recording is bounded, discarding the oldest request first
+
+	testRecorderDo(t, http.MethodHead, urlPrefix+"TestAccount/TestContainer/FooObject", nil, nil, http.StatusOK)
+
+	recordedRequests = Requests()
+	if (4 != len(recordedRequests)) || ("/v1/TestAccount/TestContainer/FooObject" != recordedRequests[0].Path) || (http.MethodHead != recordedRequests[3].Method) {
+		t.Fatalf("Requests() after overflow unexpected: %+v", recordedRequests)
+	}
+
+	// WaitForRequest() awaits asynchronously issued requests
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		testRecorderDo(t, http.MethodDelete, urlPrefix+"TestAccount/TestContainer/FooObject", nil, nil, http.StatusNoContent)
+	}()
+
+	recordedRequest, err = WaitForRequest(http.MethodDelete, "FooObject$", time.Second)
+	if nil != err {
+		t.Fatalf("WaitForRequest(DELETE,) failed: %v", err)
+	}
+	if http.StatusNoContent != recordedRequest.StatusCode {
+		t.Fatalf("WaitForRequest(DELETE,) returned unexpected request: %+v", recordedRequest)
+	}
+
+	_, err = WaitForRequest(http.MethodDelete, "BarObject$", 10*time.Millisecond)
+	if nil == err {
+		t.Fatalf("WaitForRequest() of unissued request should have timed out")
+	}
+
+	ResetRecording()
+
+	if 0 != len(Requests()) {
+		t.Fatalf("Requests() after ResetRecording() should have been empty")
+	}
+
+	DisableRecording()
+
+	testRecorderDo(t, http.MethodDelete, urlPrefix+"TestAccount/TestContainer", nil, nil, http.StatusNoContent)
+
+	if 0 != len(Requests()) {
+		t.Fatalf("Requests() after DisableRecording() should have been empty")
+	}
+
+	testRecorderDo(t, http.MethodDelete, urlPrefix+"TestAccount", nil, nil, http.StatusNoContent)
+
+	err = Stop()
+	if nil != err {
+		t.Fatalf("Stop() returned unexpected error: %v", err)
+	}
+}
+
+func testRecorderDo(t *testing.T, method string, url string, body []byte, headers map[string]string, expectedStatusCode int) {
+	var (
+		err          error
+		httpRequest  *http.Request
+		httpResponse *http.Response
+	)
+
+	httpRequest, err = http.NewRequest(method, url, bytes.NewReader(body))
+	if nil != err {
+		t.Errorf("http.NewRequest() returned unexpected error: %v", err)
+		return
+	}
+	for headerName, headerValue := range headers {
+		httpRequest.Header.Add(headerName, headerValue)
+	}
+	httpResponse, err = http.DefaultClient.Do(httpRequest)
+	if nil != err {
+		t.Errorf("http.DefaultClient.Do() returned unexpected error: %v", err)
+		return
+	}
+	_, _ = ioutil.ReadAll(httpResponse.Body)
+	_ = httpResponse.Body.Close()
+	if expectedStatusCode != httpResponse.StatusCode {
+		t.Errorf("%s of %s returned StatusCode %d, expected %d", method, url, httpResponse.StatusCode, expectedStatusCode)
+	}
+}
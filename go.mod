@@ -12,6 +12,7 @@ require (
 	github.com/coreos/go-systemd v0.0.0-20190620071333-e64a0ec8b42a // indirect
 	github.com/coreos/pkg v0.0.0-20180928190104-399ea9e2e55f // indirect
 	github.com/creachadair/cityhash v0.1.0
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/go-errors/errors v1.0.0 // indirect
 	github.com/gogo/protobuf v1.2.2-0.20190611061853-dadb62585089 // indirect
 	github.com/google/btree v1.0.0
@@ -22,7 +23,8 @@ require (
 	go.uber.org/multierr v1.5.0 // indirect
 	go.uber.org/zap v1.10.1-0.20190619185213-853ac185800f // indirect
 	golang.org/x/net v0.0.0-20190813141303-74dc4d7220e7
-	golang.org/x/sys v0.0.0-20190826190057-c7b8b68b1456
+	golang.org/x/sys v0.0.0-20191005200804-aed5e4c7ecf9
 	golang.org/x/text v0.3.2 // indirect
 	google.golang.org/genproto v0.0.0-20190620144150-6af8c5fc6601 // indirect
+	gopkg.in/yaml.v2 v2.2.2
 )
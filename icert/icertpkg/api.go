@@ -6,9 +6,20 @@
 package icertpkg
 
 import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
 	"net"
+	"sync"
 	"time"
+
+	"github.com/NVIDIA/proxyfs/conf"
 )
 
 const (
@@ -26,16 +37,402 @@ const (
 	GenerateKeyAlgorithmRSABits = 4096
 
 	// CertificateSerialNumberRandomBits is the number of bits that will
-	// be randomly generated for a certificate's SerialNumber.
+	// be randomly generated for a certificate's SerialNumber. RFC 5280
+	// limits a SerialNumber to 20 octets, which (as it must be positive)
+	// allows at most 159 bits.
 	//
-	CertificateSerialNumberRandomBits = 256
+	CertificateSerialNumberRandomBits = 159
 
-	// GeneratedFilePerm is the permission bits that, after the application
-	// of umask, will specify the mode of the created cert|key files.
+	// GeneratedFilePerm is the permission bits that will specify the mode
+	// of created files containing only a cert.
 	//
 	GeneratedFilePerm = 0644
+
+	// GeneratedKeyFilePerm is the permission bits that will specify the
+	// mode of created files containing a key (including combined files).
+	//
+	GeneratedKeyFilePerm = 0600
+)
+
+// LockFileSuffix is appended to certFile to name the lock file used when
+// CertOptions.Lock is specified. The lock file is left in place following
+// generation.
+//
+const LockFileSuffix = ".lock"
+
+// DefaultLockTimeout is how long to await a held lock when CertOptions.Lock is
+// specified with a zero CertOptions.LockTimeout.
+//
+const DefaultLockTimeout = 30 * time.Second
+
+// ErrLockHeld is returned when CertOptions.Lock is specified and the lock on
+// LockFile remains held by another generator after CertOptions.LockTimeout.
+// PID is the process ID of the holder if known (or zero otherwise).
+//
+type ErrLockHeld struct {
+	LockFile string
+	PID      int
+}
+
+// Error returns a description of the held lock.
+//
+func (errLockHeld *ErrLockHeld) Error() string {
+	return errLockHeld.error()
+}
+
+// ErrCAExpired is returned (wrapped) when a CA Certificate is used for
+// issuance after its NotAfter has passed.
+//
+var ErrCAExpired = errors.New("CA Certificate has expired")
+
+// ErrExceedsCAExpiry is returned (wrapped) when a Certificate to be issued by a
+// CA would remain valid after the CA Certificate's NotAfter (see
+// CertOptions.ClampToCAExpiry).
+//
+var ErrExceedsCAExpiry = errors.New("Certificate validity would extend past CA Certificate's NotAfter")
+
+// CAExpiryClampTolerance is how far past its issuing CA Certificate's NotAfter
+// an Endpoint Certificate's NotAfter may fall and yet be silently truncated to the
+// CA's NotAfter even without CertOptions.ClampToCAExpiry. This absorbs the skew
+// between generating a CA Certificate and its Endpoint Certificates with the same
+// ttl moments apart (compounded by NotAfter only being encoded to the second).
+//
+const CAExpiryClampTolerance = time.Minute
+
+// Errors returned (wrapped) by certificate generation (and LoadCA()) that callers
+// may react to via errors.Is(). ErrUnsupportedKeyAlgorithm indicates an unknown
+// generateKeyAlgorithm, ErrCANotFound that the CA Certificate or private key file
+// does not exist, and ErrKeyCertMismatch that a Certificate was not issued for
+// the private key it was paired with.
+//
+var (
+	ErrUnsupportedKeyAlgorithm = errors.New("generateKeyAlgorithm not supported")
+	ErrCANotFound              = errors.New("CA Certificate or private key file not found")
+	ErrKeyCertMismatch         = errors.New("Certificate does not match private key")
+)
+
+// ErrBadPEM is returned when Path does not contain the expected PEM-encoded
+// content. BlockIndex is the (zero-based) index of the PEM block that could not
+// be parsed or -1 if no suitable PEM block was found at all. Err describes the
+// underlying failure.
+//
+type ErrBadPEM struct {
+	Path       string
+	BlockIndex int
+	Err        error
+}
+
+// Error returns a description of the bad PEM content.
+//
+func (errBadPEM *ErrBadPEM) Error() string {
+	return errBadPEM.error()
+}
+
+// Unwrap returns the underlying failure.
+//
+func (errBadPEM *ErrBadPEM) Unwrap() error {
+	return errBadPEM.Err
+}
+
+// Errors returned (wrapped) by VerifyEndpointCert() distinguishing why the
+// Certificate failed verification.
+//
+var (
+	ErrCertExpired      = errors.New("Certificate has expired")
+	ErrCertNotYetValid  = errors.New("Certificate is not yet valid")
+	ErrUnknownAuthority = errors.New("Certificate signed by unknown authority")
+	ErrNameMismatch     = errors.New("Certificate not valid for name")
+	ErrNameNotPermitted = errors.New("Certificate has a SAN excluded by its issuer's name constraints")
+	ErrPathLenExceeded  = errors.New("Certificate chain exceeds a CA Certificate's path length constraint")
+)
+
+// ErrSANMismatch is returned (wrapped) by Endpoint (and self-signed) Certificate
+// generation should the generated Certificate, parsed back prior to being
+// written, not hold each requested DNS and IP SAN (e.g. because an element of
+// ipAddresses was the nil returned by net.ParseIP() of a malformed string).
+//
+var ErrSANMismatch = errors.New("generated Certificate lacks a requested SAN")
+
+// ErrExtensionNotFound is returned (wrapped) by ReadExtension() when the
+// Certificate lacks an extension with the requested OID.
+//
+var ErrExtensionNotFound = errors.New("Certificate lacks extension")
+
+// ErrIssuanceCallbackPanic is returned (wrapped) by the generation of a
+// Certificate (already written) whose issuance callback (see
+// SetIssuanceCallback()) panicked.
+//
+var ErrIssuanceCallbackPanic = errors.New("issuance callback panicked")
+
+// ErrInvalidCertID is returned (wrapped) by a FileCertStore asked for an id
+// that is empty or contains a path separator or ".." (and so might name a file
+// outside its directory).
+//
+var ErrInvalidCertID = errors.New("invalid FileCertStore id")
+
+// ErrPinMismatch is returned (wrapped) during the handshake of a tls.Config from
+// BuildPinnedClientTLSConfig() should the server's Certificate not be pinned.
+//
+var ErrPinMismatch = errors.New("Certificate fingerprint not pinned")
+
+// Errors returned (wrapped) by VerifyCertWasIssuedByCA() distinguishing why the
+// Certificate was not found to have been issued by the CA Certificate.
+//
+var (
+	ErrNotCACert         = errors.New("Certificate is not a CA Certificate")
+	ErrSignatureMismatch = errors.New("Certificate signature not made by CA Certificate")
+)
+
+// CertOptions specifies optional behavior of certificate generation. A nil
+// *CertOptions or the zero value selects the default behavior.
+//
+type CertOptions struct {
+	// Overwrite permits GenCACertWithOptions() to replace an existing CA
+	// private key file. By default, an existing keyFile is left untouched
+	// and an error wrapping os.ErrExist is returned.
+	//
+	Overwrite bool
+
+	// Constraints limits what a generated CA Certificate may sign. It may not
+	// be specified when generating any other Certificate.
+	//
+	Constraints CAConstraints
+
+	// ExistingKeyFile, if specified, names a PEM-encoded private key file (in
+	// PKCS#8, PKCS#1, or SEC 1 "EC PRIVATE KEY" form) for whose public key the
+	// Certificate is generated instead of generating a new key. The key file is
+	// left untouched and only certFile (which must differ from ExistingKeyFile)
+	// is written. The keyFile argument must be empty or ExistingKeyFile. A
+	// generateKeyAlgorithm of "" accepts any key, otherwise it must match it.
+	//
+	ExistingKeyFile string
+
+	// ExistingKey, if non-nil, is a pre-generated private key (e.g. from GenKey()
+	// or LoadKeyPEM()) used instead of generating a new key. Unlike ExistingKeyFile,
+	// it is written to keyFile just as a newly generated key would be. It must
+	// implement crypto.Signer, may not be combined with ExistingKeyFile, and a
+	// generateKeyAlgorithm of "" accepts any key, otherwise it must match it.
+	//
+	ExistingKey crypto.PrivateKey
+
+	// NotBefore, if non-zero, replaces Now() as the start of a generated
+	// Certificate's validity (which then lasts for ttl from NotBefore). It may
+	// be in the past (backdating) or the future (pre-staging).
+	//
+	NotBefore time.Time
+
+	// ClampToCAExpiry, if true, truncates the NotAfter of an Endpoint Certificate
+	// that would otherwise (given NotBefore and ttl) outlive its issuing CA
+	// Certificate to the CA's NotAfter. By default, such a request fails with an
+	// error wrapping ErrExceedsCAExpiry (unless within CAExpiryClampTolerance). A
+	// Certificate whose NotBefore does not precede the CA's NotAfter fails either
+	// way.
+	//
+	ClampToCAExpiry bool
+
+	// Rand, if non-nil, replaces crypto/rand.Reader as the source of randomness
+	// for generating private keys and SerialNumbers (other than those assigned
+	// by GenEndpointCerts()) and for signing. Now, if non-nil, replaces
+	// time.Now() both as the default NotBefore and when checking expiry (e.g.
+	// of the issuing CA or for SkipIfValidFor). Given a deterministic Rand and
+	// a fixed Now, Ed25519 generation is byte-for-byte reproducible (RSA key
+	// generation deliberately is not).
+	//
+	Rand io.Reader
+	Now  func() time.Time
+
+	// Usage overrides the KeyUsage and/or ExtKeyUsage of a generated
+	// Certificate.
+	//
+	Usage CertUsageOptions
+
+	// LintAllow lists the LintIDs of lint checks whose violations are to be
+	// ignored when generating a Certificate.
+	//
+	LintAllow []LintID
+
+	// LintWarn, if non-nil, downgrades lint violations from failing the
+	// generation of a Certificate to warnings passed to LintWarn (prior to
+	// the Certificate being written).
+	//
+	LintWarn func(violations []LintViolation)
+
+	// Lock, if true, serializes generation into the same certFile (across both
+	// goroutines and processes) by holding an advisory lock (flock on unix,
+	// LockFileEx on windows) on certFile+LockFileSuffix for the duration. The
+	// lock is awaited for up to LockTimeout (or DefaultLockTimeout if zero),
+	// following which an *ErrLockHeld is returned.
+	//
+	Lock        bool
+	LockTimeout time.Duration
+
+	// SkipIfValidFor, if positive, skips generation if certFile and keyFile
+	// (or ExistingKeyFile) already hold a matching pair, signed by the issuing
+	// CA (or self-signed if generating a CA or self-signed Certificate), that
+	// remains valid for at least SkipIfValidFor. Neither the Subject nor SANs
+	// of an existing Certificate are compared. When combined with Lock, the
+	// check is made under the lock so racing generators converge on a single
+	// Certificate. If non-nil, OnSkip is called when generation is skipped.
+	//
+	SkipIfValidFor time.Duration
+	OnSkip         func()
+
+	// OCSPResponderURL, if non-empty, is placed in the Authority Information
+	// Access extension of a generated Certificate as its first OCSP responder.
+	//
+	// Deprecated: List it in OCSPServerURLs instead. It remains an alias for a
+	// first entry of OCSPServerURLs, placed ahead of those listed there.
+	//
+	OCSPResponderURL string
+
+	// IssuingCertificateURL, if non-empty, is placed in the Authority
+	// Information Access extension of a generated Certificate as the first
+	// location from which its issuing CA Certificate may be fetched.
+	//
+	// Deprecated: List it in IssuingCertificateURLs instead. It remains an
+	// alias for a first entry of IssuingCertificateURLs, placed ahead of those
+	// listed there.
+	//
+	IssuingCertificateURL string
+
+	// CRLDistributionPoints, if non-empty, lists the URLs (each of which must be
+	// absolute) from which a CRL covering a generated Certificate may be fetched.
+	//
+	CRLDistributionPoints []string
+
+	// OCSPServerURLs and IssuingCertificateURLs, if non-empty, list the OCSP
+	// responder and issuing CA Certificate URLs (each of which must be an
+	// absolute http or https URL) placed, in order, in the Authority Information
+	// Access extension of a generated Certificate so that clients may check its
+	// revocation status online. Any of these revocation URLs specified for an
+	// Endpoint Certificate issued via a *CA override its IssuedCertURLs of the
+	// same kind.
+	//
+	OCSPServerURLs         []string
+	IssuingCertificateURLs []string
+
+	// SignatureAlgorithm, if not x509.UnknownSignatureAlgorithm, replaces the
+	// default signature algorithm of the issuer's key (e.g. x509.SHA256WithRSA
+	// for an RSA key) with which a generated Certificate is signed. It must be
+	// one of the RSA (PKCS#1 v1.5 or PSS), ECDSA, or Ed25519 SHA-2 based
+	// algorithms and match the type of the issuer's key (e.g. x509.SHA384WithRSAPSS
+	// is rejected for an Ed25519 CA).
+	//
+	SignatureAlgorithm x509.SignatureAlgorithm
+
+	// ExtraExtensions are added, as is, to a generated Certificate (e.g. for a
+	// private OID). Any of them may be marked Critical though ensuring that
+	// every relying party understands such an extension is the caller's
+	// responsibility. MustStaple, if true, adds the TLS Feature extension
+	// (RFC 7633) requiring the OCSP status_request TLS extension (i.e. OCSP
+	// Must-Staple) as is typically wanted only of a server Certificate. An
+	// ExtraExtensions OID matching either that of an extension icertpkg
+	// generates itself (e.g. Key Usage or Subject Alternative Name), that of
+	// MustStaple (if set), or that of another ExtraExtensions entry is rejected.
+	//
+	ExtraExtensions []pkix.Extension
+	MustStaple      bool
+
+	// Extensions are added to a generated Certificate following ExtraExtensions
+	// (and subject to the same OID checks) for callers preferring to describe a
+	// custom extension (e.g. carrying policy metadata or a device attestation
+	// claim under a private OID) as a CertExtension. ReadExtension() returns the
+	// Value of such an extension from the generated Certificate.
+	//
+	Extensions []CertExtension
+
+	// CommonName, if non-empty, becomes the Subject.CommonName of a generated
+	// Certificate (for legacy clients matching on it while ignoring SANs). It
+	// may not conflict with a CommonName already in subject. As modern clients
+	// ignore CommonName, an Endpoint (or self-signed) Certificate's CommonName
+	// must, given a CommonNameSANMode of CommonNameSANRequire (the default if
+	// empty), also be one of its DNS SANs (or, if an IP Address, IP SANs) or
+	// generation fails. A CommonNameSANMode of CommonNameSANAppend instead adds
+	// a missing CommonName to the SANs.
+	//
+	CommonName        string
+	CommonNameSANMode string
+
+	// serialNumber, if non-nil, is used in place of a randomly generated
+	// SerialNumber (e.g. by GenEndpointCerts() to ensure uniqueness)
+	//
+	serialNumber *big.Int
+
+	// certStore, if non-nil, receives the generated Certificate and private key
+	// (taking certFile and keyFile as their ids) in place of writing files (e.g.
+	// for GenCACertToStore())
+	//
+	certStore CertStore
+
+	// issuanceKind, if non-empty, replaces the IssuanceRecord.Kind otherwise
+	// reported for the generated Certificate (e.g. IssuanceKindEndpointRenewal)
+	//
+	issuanceKind string
+
+	// renewedRevocationURLs is set when OCSPServerURLs and IssuingCertificateURLs
+	// were copied from the Certificate being renewed (e.g. an OCSPResponderURL
+	// that needed only be absolute) so are not held to their usual http or https
+	// check
+	//
+	renewedRevocationURLs bool
+}
+
+// CertExtension describes a custom X.509 extension (see CertOptions.Extensions)
+// identified by OID. Value is the extension's DER-encoded content placed, as is,
+// in the extnValue OCTET STRING (e.g. as produced by asn1.Marshal()).
+//
+type CertExtension struct {
+	OID      asn1.ObjectIdentifier
+	Critical bool
+	Value    []byte
+}
+
+// CertOptions.CommonNameSANMode values.
+//
+const (
+	CommonNameSANRequire = "require"
+	CommonNameSANAppend  = "append"
 )
 
+// CertUsageOptions specifies the KeyUsage and ExtKeyUsage of a generated
+// Certificate. A zero KeyUsage selects x509.KeyUsageDigitalSignature for an
+// Endpoint Certificate and additionally x509.KeyUsageCertSign for a CA Certificate.
+// A nil ExtKeyUsage selects both x509.ExtKeyUsageClientAuth and x509.ExtKeyUsageServerAuth.
+// UnknownExtKeyUsage lists further ExtKeyUsage OIDs (e.g. private ones) to be
+// included alongside ExtKeyUsage.
+//
+// A non-nil but empty ExtKeyUsage is rejected (unless UnknownExtKeyUsage is
+// non-empty, selecting only its OIDs), as is a CA Certificate KeyUsage lacking
+// x509.KeyUsageCertSign and an Endpoint Certificate KeyUsage including either
+// x509.KeyUsageCertSign or x509.KeyUsageCRLSign.
+//
+type CertUsageOptions struct {
+	KeyUsage           x509.KeyUsage
+	ExtKeyUsage        []x509.ExtKeyUsage
+	UnknownExtKeyUsage []asn1.ObjectIdentifier
+}
+
+// CAConstraints specifies the path length limit and the X.509 name constraints
+// of a generated CA Certificate (the latter marked critical). A MaxPathLen of
+// -1 (or zero unless MaxPathLenZero is set) means any number of intermediate
+// CAs may follow, zero with MaxPathLenZero set means none may, and a positive
+// MaxPathLen means at most that many may. A DNS or email domain
+// constraint matches that domain and any subdomain while one starting with '.'
+// (e.g. ".example.com") matches only subdomains. An email constraint containing
+// '@' matches only that address. Endpoint Certificates issued by icertpkg are
+// checked against their CA's name constraints prior to being generated.
+//
+type CAConstraints struct {
+	MaxPathLen              int
+	MaxPathLenZero          bool
+	PermittedDNSDomains     []string
+	ExcludedDNSDomains      []string
+	PermittedIPRanges       []*net.IPNet
+	ExcludedIPRanges        []*net.IPNet
+	PermittedEmailAddresses []string
+	ExcludedEmailAddresses  []string
+}
+
 // GenCACert is called to generate a Certificate Authority using the requested
 // generateKeyAlgorithm for the specified subject who's validity last for the
 // desired ttl starting from time.Now(). The resultant PEM-encoded CA Certificate
@@ -43,21 +440,1417 @@ const (
 // written to keyFile. If certFile and keyFile are the same, both the CA Certificate
 // and its private key will be written to the common file.
 //
+// Each file is written to a temporary file in its destination directory and then
+// renamed into place, so a crash never leaves a truncated file behind. Files
+// containing the private key are given mode GeneratedKeyFilePerm while a certFile
+// distinct from keyFile is given mode GeneratedFilePerm. If keyFile already exists,
+// an error wrapping os.ErrExist is returned (see GenCACertWithOptions()).
+//
 func GenCACert(generateKeyAlgorithm string, subject pkix.Name, ttl time.Duration, certFile string, keyFile string) (err error) {
-	return genCACert(generateKeyAlgorithm, subject, ttl, certFile, keyFile)
+	return genCACert(context.Background(), generateKeyAlgorithm, subject, ttl, certFile, keyFile, nil)
+}
+
+// GenCACertWithOptions is called to generate a Certificate Authority just like
+// GenCACert() but with the optional behavior specified by options.
+//
+func GenCACertWithOptions(generateKeyAlgorithm string, subject pkix.Name, ttl time.Duration, certFile string, keyFile string, options *CertOptions) (err error) {
+	return genCACert(context.Background(), generateKeyAlgorithm, subject, ttl, certFile, keyFile, options)
+}
+
+// GenCACertContext is called to generate a Certificate Authority just like
+// GenCACertWithOptions() but abandoning generation, with an error wrapping
+// ctx.Err() and without writing certFile or keyFile, should ctx be cancelled
+// (e.g. while awaiting Lock or generating a private key).
+//
+func GenCACertContext(ctx context.Context, generateKeyAlgorithm string, subject pkix.Name, ttl time.Duration, certFile string, keyFile string, options *CertOptions) (err error) {
+	return genCACert(ctx, generateKeyAlgorithm, subject, ttl, certFile, keyFile, options)
+}
+
+// GenCACertWithKey is called to generate a Certificate Authority just like
+// GenCACert() but for the pre-generated privateKey rather than a newly generated
+// one. The privateKey is written to keyFile (see CertOptions.ExistingKey).
+//
+func GenCACertWithKey(privateKey crypto.PrivateKey, subject pkix.Name, ttl time.Duration, certFile string, keyFile string) (err error) {
+	return genCACert(context.Background(), "", subject, ttl, certFile, keyFile, &CertOptions{ExistingKey: privateKey})
 }
 
 // GenEndpointCert is called to generate a Certificate using the requested
 // generateKeyAlgorithm for the specified subject who's validity lasts for
 // the desired ttl starting from time.Now(). The endpoints for which the
-// Certificate will apply will be the specified dnsNames and/or ipAddresses.
+// Certificate will apply will be the specified dnsNames, ipAddresses, (RFC 822)
+// emailAddresses, and/or uris (e.g. "spiffe://trust-domain/workload"). Each of
+// emailAddresses must contain precisely one '@' separating a non-empty local part
+// from a non-empty domain. Each of uris must parse as an absolute URI.
 // The Certificate will be signed by the CA Certificate specified via caCertFile
 // and caKeyFile. The caCertFile and caKeyFile values may be identical. The
 // resultant PEM-encoded Certificate is written to certFile. The PEM-encoded
 // private key for the Certificate is written to keyFile. If certFile and
 // keyFile are the same, both the Certificate and its private key will be
-// written to the common file.
+// written to the common file. The files are written as described for GenCACert()
+// except that existing files are always replaced.
+//
+func GenEndpointCert(generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, caCertFile string, caKeyFile string, endpointCertFile string, endpointKeyFile string) (err error) {
+	return genEndpointCert(context.Background(), generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, caCertFile, caKeyFile, endpointCertFile, endpointKeyFile, nil)
+}
+
+// GenEndpointCertWithOptions is called to generate a Certificate just like
+// GenEndpointCert() but with the optional behavior specified by options.
+//
+func GenEndpointCertWithOptions(generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, caCertFile string, caKeyFile string, endpointCertFile string, endpointKeyFile string, options *CertOptions) (err error) {
+	return genEndpointCert(context.Background(), generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, caCertFile, caKeyFile, endpointCertFile, endpointKeyFile, options)
+}
+
+// GenEndpointCertContext is called to generate a Certificate just like
+// GenEndpointCertWithOptions() but abandoning generation as described for
+// GenCACertContext() should ctx be cancelled.
+//
+func GenEndpointCertContext(ctx context.Context, generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, caCertFile string, caKeyFile string, endpointCertFile string, endpointKeyFile string, options *CertOptions) (err error) {
+	return genEndpointCert(ctx, generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, caCertFile, caKeyFile, endpointCertFile, endpointKeyFile, options)
+}
+
+// GenEndpointCertWithKey is called to generate a Certificate just like
+// GenEndpointCert() but for the pre-generated privateKey rather than a newly
+// generated one. The privateKey is written to endpointKeyFile (see
+// CertOptions.ExistingKey).
+//
+func GenEndpointCertWithKey(privateKey crypto.PrivateKey, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, caCertFile string, caKeyFile string, endpointCertFile string, endpointKeyFile string) (err error) {
+	return genEndpointCert(context.Background(), "", subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, caCertFile, caKeyFile, endpointCertFile, endpointKeyFile, &CertOptions{ExistingKey: privateKey})
+}
+
+// GenSelfSignedCert is called to generate a self-signed (i.e. not CA issued)
+// Certificate for use by a server. Other than not requiring (nor being usable
+// as) a CA and defaulting to only x509.ExtKeyUsageServerAuth, the arguments and
+// resultant files are as described for GenEndpointCert(). A client trusts such
+// a Certificate by adding it, alone, to its RootCAs.
+//
+func GenSelfSignedCert(generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, ttl time.Duration, certFile string, keyFile string) (err error) {
+	return genSelfSignedCert(context.Background(), generateKeyAlgorithm, subject, dnsNames, ipAddresses, ttl, certFile, keyFile, nil)
+}
+
+// GenSelfSignedCertWithOptions is called to generate a self-signed Certificate
+// just like GenSelfSignedCert() but with the optional behavior specified by options.
+//
+func GenSelfSignedCertWithOptions(generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, ttl time.Duration, certFile string, keyFile string, options *CertOptions) (err error) {
+	return genSelfSignedCert(context.Background(), generateKeyAlgorithm, subject, dnsNames, ipAddresses, ttl, certFile, keyFile, options)
+}
+
+// GenSelfSignedCertContext is called to generate a self-signed Certificate just
+// like GenSelfSignedCertWithOptions() but abandoning generation as described for
+// GenCACertContext() should ctx be cancelled.
+//
+func GenSelfSignedCertContext(ctx context.Context, generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, ttl time.Duration, certFile string, keyFile string, options *CertOptions) (err error) {
+	return genSelfSignedCert(ctx, generateKeyAlgorithm, subject, dnsNames, ipAddresses, ttl, certFile, keyFile, options)
+}
+
+// CA is a loaded Certificate Authority. The CA Certificate and its private key
+// (or a crypto.Signer holding it) are read and parsed once by LoadCA() (or
+// LoadCAWithSigner()) and then reused for every Endpoint Certificate issued. A
+// CA is safe for concurrent use by multiple goroutines.
+//
+type CA struct {
+	x509Certificate *x509.Certificate
+	signer          crypto.Signer
+	issuedCertURLs  IssuedCertURLs
+}
+
+// IssuedCertURLs lists the revocation URLs (each of which must be an absolute
+// http or https URL) placed in every Endpoint Certificate issued via a *CA
+// returned by (*CA).WithIssuedCertURLs(). CRLDistributionPoints locate the
+// CRLs covering them while OCSPServerURLs and IssuingCertificateURLs populate
+// their Authority Information Access extension. Each kind is only a default:
+// specifying any URL of the same kind in the CertOptions of a request (e.g. in
+// CertOptions.OCSPServerURLs or its deprecated alias OCSPResponderURL)
+// replaces it.
+//
+type IssuedCertURLs struct {
+	CRLDistributionPoints  []string
+	OCSPServerURLs         []string
+	IssuingCertificateURLs []string
+}
+
+// LoadCA is called to read and parse the CA Certificate specified via caCertFile
+// and caKeyFile. The caCertFile and caKeyFile values may be identical. If the CA
+// Certificate has already expired, an error wrapping ErrCAExpired is returned.
+//
+func LoadCA(caCertFile string, caKeyFile string) (ca *CA, err error) {
+	return loadCA(caCertFile, caKeyFile)
+}
+
+// LoadCAWithSigner is called to read and parse the CA Certificate in caCertFile
+// whose private key is held by signer (e.g. one backed by an HSM or KMS) such
+// that it need never be exported. Every Certificate this CA issues is signed
+// via signer.Sign(). If the CA Certificate is not a CA Certificate, an error
+// wrapping ErrNotCACert is returned. If signer.Public() is not the public key of
+// the CA Certificate, an error wrapping ErrKeyCertMismatch is returned. Any
+// private key in caCertFile is ignored. Otherwise, it behaves as LoadCA().
+//
+func LoadCAWithSigner(caCertFile string, signer crypto.Signer) (ca *CA, err error) {
+	return loadCAWithSigner(caCertFile, signer)
+}
+
+// WithIssuedCertURLs is called to obtain a copy of ca (leaving ca untouched)
+// that places issuedCertURLs in each Endpoint Certificate it issues. As the
+// CA Certificate file itself does not record them, they must be supplied anew
+// each time the CA is loaded (e.g. via LoadCA()).
+//
+func (ca *CA) WithIssuedCertURLs(issuedCertURLs IssuedCertURLs) (caWithIssuedCertURLs *CA, err error) {
+	return ca.withIssuedCertURLs(issuedCertURLs)
+}
+
+// GenEndpointCert is called to generate a Certificate signed by this CA. Other
+// than taking the CA from the receiver rather than from caCertFile and caKeyFile,
+// it behaves identically to the GenEndpointCert() func. If the CA Certificate
+// has expired since LoadCA() was called, an error wrapping ErrCAExpired is returned.
+//
+func (ca *CA) GenEndpointCert(generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, endpointCertFile string, endpointKeyFile string) (err error) {
+	return ca.genEndpointCert(context.Background(), generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, endpointCertFile, endpointKeyFile, nil)
+}
+
+// GenEndpointCertWithOptions is called to generate a Certificate signed by this
+// CA just like (*CA).GenEndpointCert() but with the optional behavior specified
+// by options.
+//
+func (ca *CA) GenEndpointCertWithOptions(generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, endpointCertFile string, endpointKeyFile string, options *CertOptions) (err error) {
+	return ca.genEndpointCert(context.Background(), generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, endpointCertFile, endpointKeyFile, options)
+}
+
+// GenEndpointCertContext is called to generate a Certificate signed by this CA
+// just like (*CA).GenEndpointCertWithOptions() but abandoning generation as
+// described for GenCACertContext() should ctx be cancelled.
+//
+func (ca *CA) GenEndpointCertContext(ctx context.Context, generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, endpointCertFile string, endpointKeyFile string, options *CertOptions) (err error) {
+	return ca.genEndpointCert(ctx, generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, endpointCertFile, endpointKeyFile, options)
+}
+
+// GenEndpointCertWithKey is called to generate a Certificate signed by this CA
+// just like (*CA).GenEndpointCert() but for the pre-generated privateKey rather
+// than a newly generated one. The privateKey is written to endpointKeyFile (see
+// CertOptions.ExistingKey).
+//
+func (ca *CA) GenEndpointCertWithKey(privateKey crypto.PrivateKey, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, endpointCertFile string, endpointKeyFile string) (err error) {
+	return ca.genEndpointCert(context.Background(), "", subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, endpointCertFile, endpointKeyFile, &CertOptions{ExistingKey: privateKey})
+}
+
+// GenKey is called to generate a private key using the requested
+// generateKeyAlgorithm (e.g. for use by GenCACertWithKey() or
+// GenEndpointCertWithKey()). The returned key implements crypto.Signer.
+//
+func GenKey(generateKeyAlgorithm string) (privateKey crypto.PrivateKey, err error) {
+	return genKey(generateKeyAlgorithm)
+}
+
+// SaveKeyPEM is called to write key to outPath in the PEM encoding specific to
+// its algorithm: PKCS#1 ("RSA PRIVATE KEY") for RSA, SEC 1 ("EC PRIVATE KEY")
+// for ECDSA, and PKCS#8 ("PRIVATE KEY") for Ed25519. The file is written as
+// described for GenCACert() with mode GeneratedKeyFilePerm. If outPath already
+// exists, an error wrapping os.ErrExist is returned.
+//
+func SaveKeyPEM(key crypto.PrivateKey, outPath string) (err error) {
+	return saveKeyPEM(key, outPath)
+}
+
+// SaveKeyPEMPKCS8 is called to write key to outPath just like SaveKeyPEM() but
+// always in the PKCS#8 ("PRIVATE KEY") encoding as required by some TLS
+// libraries and Java keystores.
+//
+func SaveKeyPEMPKCS8(key crypto.PrivateKey, outPath string) (err error) {
+	return saveKeyPEMPKCS8(key, outPath)
+}
+
+// LoadKeyPEM is called to read the first private key found in keyPEMPath (which
+// may also hold other PEM blocks such as a "CERTIFICATE"). PKCS#8, PKCS#1, and
+// SEC 1 encodings are supported. The returned key implements crypto.Signer.
+//
+func LoadKeyPEM(keyPEMPath string) (privateKey crypto.PrivateKey, err error) {
+	return loadKeyPEM(keyPEMPath)
+}
+
+// LoadKeyPEMPKCS8 is called to read the first PKCS#8 ("PRIVATE KEY") private key
+// found in keyPEMPath. Unlike LoadKeyPEM(), algorithm-specific encodings are
+// skipped such that, should keyPEMPath hold no PKCS#8 private key, an *ErrBadPEM
+// is returned. The returned key implements crypto.Signer.
+//
+func LoadKeyPEMPKCS8(keyPEMPath string) (privateKey crypto.PrivateKey, err error) {
+	return loadKeyPEMPKCS8(keyPEMPath)
+}
+
+// LoadCertKeyPEM is called to read the combined Certificate and private key file
+// path however it was laid out: the Certificate and private key blocks may be in
+// any order, lines may end in CRLF, non-PEM text (e.g. explanations) between the
+// blocks is ignored, and the private key may be PKCS#8, PKCS#1, or SEC 1 encoded.
+// The leaf of the returned tls.Certificate (also set as its Leaf) is whichever
+// Certificate was issued for the (first) private key with any others (e.g. an
+// intermediate CA Certificate) following it. Should no Certificate match the
+// private key, an error wrapping ErrKeyCertMismatch is returned. Every other API
+// reading a Certificate and its private key (e.g. LoadCA() and NewCertManager())
+// does so in this same way.
+//
+func LoadCertKeyPEM(path string) (tlsCertificate tls.Certificate, err error) {
+	return loadKeyPair(path, path)
+}
+
+// CertManager holds a Certificate and its private key loaded from PEM files that
+// are periodically reloaded so that a long-running service picks up rotated
+// files without restarting. A CertManager is safe for concurrent use by multiple
+// goroutines.
+//
+type CertManager struct {
+	sync.RWMutex
+	certFile       string
+	keyFile        string
+	reloadInterval time.Duration
+	tlsCertificate *tls.Certificate
+	lastReloadErr  error
+	stopChan       chan struct{}
+	stopOnce       sync.Once
+	stopWG         sync.WaitGroup
+}
+
+// NewCertManager is called to load the Certificate specified via certFile and
+// keyFile and launch a goroutine that reloads them every reloadInterval. The
+// certFile and keyFile values may be identical. If a reload fails, the
+// previously loaded Certificate continues to be served.
+//
+func NewCertManager(certFile string, keyFile string, reloadInterval time.Duration) (certManager *CertManager, err error) {
+	return newCertManager(certFile, keyFile, reloadInterval)
+}
+
+// GetCertificate returns the currently loaded Certificate. It is suitable for
+// use as the GetCertificate callback of a tls.Config.
+//
+func (certManager *CertManager) GetCertificate(clientHelloInfo *tls.ClientHelloInfo) (tlsCertificate *tls.Certificate, err error) {
+	return certManager.getCertificate(clientHelloInfo)
+}
+
+// LastReloadErr returns the error from the most recent reload attempt (or nil if
+// it succeeded).
+//
+func (certManager *CertManager) LastReloadErr() (err error) {
+	return certManager.getLastReloadErr()
+}
+
+// Stop is called to stop the goroutine that reloads the Certificate. The last
+// loaded Certificate continues to be returned by GetCertificate(). Calling Stop
+// more than once is harmless.
+//
+func (certManager *CertManager) Stop() {
+	certManager.stop()
+}
+
+// CertReloaderDefaultPollInterval is the interval at which a CertReloader
+// created via NewCertReloader() (or with a zero PollInterval) checks the
+// modification times of its PEM files.
+//
+const CertReloaderDefaultPollInterval = 10 * time.Second
+
+// CertReloaderOptions supplies optional tuning of a CertReloader.
+//
+// If PollInterval is zero, CertReloaderDefaultPollInterval is used. If negative,
+// the PEM files are only re-read when Reload() is called.
+//
+// If OnReloadError is non-nil, it is called (from the polling goroutine) each
+// time a reload triggered by a changed modification time fails. Such a reload is
+// retried at each subsequent poll until it succeeds.
+//
+type CertReloaderOptions struct {
+	PollInterval  time.Duration
+	OnReloadError func(err error)
+}
+
+// CertReloader holds a Certificate and its private key loaded from PEM files
+// that are re-read whenever either file's modification time changes or Reload()
+// is called. A half-written or mismatched cert/key pair never replaces the one
+// currently being served. A CertReloader is safe for concurrent use by multiple
+// goroutines.
+//
+type CertReloader struct {
+	sync.RWMutex
+	certPath       string
+	keyPath        string
+	options        CertReloaderOptions
+	tlsCertificate *tls.Certificate
+	certModTime    time.Time
+	keyModTime     time.Time
+	stopOnce       sync.Once
+	stopChan       chan struct{}
+	stopWG         sync.WaitGroup
+}
+
+// NewCertReloader is called to load the Certificate specified via certPath and
+// keyPath and launch a goroutine that polls for them being modified every
+// CertReloaderDefaultPollInterval. The certPath and keyPath values may be
+// identical.
+//
+func NewCertReloader(certPath string, keyPath string) (certReloader *CertReloader, err error) {
+	return newCertReloader(certPath, keyPath, nil)
+}
+
+// NewCertReloaderWithOptions is identical to NewCertReloader() except that the
+// polling interval and reload error callback are specified via options (which
+// may be nil).
+//
+func NewCertReloaderWithOptions(certPath string, keyPath string, options *CertReloaderOptions) (certReloader *CertReloader, err error) {
+	return newCertReloader(certPath, keyPath, options)
+}
+
+// GetCertificate returns the currently loaded Certificate. It is suitable for
+// use as the GetCertificate callback of a tls.Config.
+//
+func (certReloader *CertReloader) GetCertificate(clientHelloInfo *tls.ClientHelloInfo) (tlsCertificate *tls.Certificate, err error) {
+	return certReloader.getCertificate()
+}
+
+// GetClientCertificate returns the currently loaded Certificate. It is suitable
+// for use as the GetClientCertificate callback of a tls.Config.
+//
+func (certReloader *CertReloader) GetClientCertificate(certificateRequestInfo *tls.CertificateRequestInfo) (tlsCertificate *tls.Certificate, err error) {
+	return certReloader.getCertificate()
+}
+
+// Reload is called to immediately re-read the PEM files. If they fail to load
+// (or do not match), err is returned and the previously loaded Certificate
+// continues to be served.
+//
+func (certReloader *CertReloader) Reload() (err error) {
+	return certReloader.reload()
+}
+
+// Stop is called to stop the polling goroutine. The last loaded Certificate
+// continues to be returned by GetCertificate() and GetClientCertificate().
+//
+func (certReloader *CertReloader) Stop() {
+	certReloader.stop()
+}
+
+// WatchAndReload is called to load the tls.Certificate in certPEMPath and
+// keyPEMPath and then watch (via fsnotify) for either file being written or
+// (e.g. by icertpkg's atomic rename) created. Following each such change, both
+// files are reloaded and onChange is called with the new tls.Certificate. If the
+// reload fails, onChange is instead called with the last successfully loaded
+// tls.Certificate and a non-nil err, as it also is should fsnotify report an
+// error (e.g. its event queue overflowing, so a change may have been missed).
+// The onChange func must not be nil. The returned stop func ends the watching.
+//
+func WatchAndReload(certPEMPath string, keyPEMPath string, onChange func(cert tls.Certificate, err error)) (stop func(), err error) {
+	return watchAndReload(certPEMPath, keyPEMPath, onChange)
+}
+
+// LintID identifies a lint check. Its value is stable and may be used to
+// allowlist a check (see CertOptions.LintAllow).
+//
+type LintID string
+
+const (
+	// LintIDValidityTooLong is reported for an Endpoint Certificate usable for
+	// ExtKeyUsageServerAuth whose validity exceeds LintMaxServerAuthValidity.
+	//
+	LintIDValidityTooLong LintID = "validity-too-long"
+
+	// LintIDCommonNameWithoutSAN is reported for an Endpoint Certificate with a
+	// Subject.CommonName but no Subject Alternative Names.
+	//
+	LintIDCommonNameWithoutSAN LintID = "common-name-without-san"
+
+	// LintIDSHA1Signature is reported for a Certificate signed using SHA-1.
+	//
+	LintIDSHA1Signature LintID = "sha1-signature"
+
+	// LintIDSerialNumberEncoding is reported for a Certificate with a missing,
+	// non-positive, or longer than 20 octet SerialNumber.
+	//
+	LintIDSerialNumberEncoding LintID = "serial-number-encoding"
+
+	// LintMaxServerAuthValidity is the longest validity accepted for an Endpoint
+	// Certificate usable for ExtKeyUsageServerAuth by common verifiers.
+	//
+	LintMaxServerAuthValidity = 398 * 24 * time.Hour
+)
+
+// LintViolation describes a single failed lint check.
+//
+type LintViolation struct {
+	ID      LintID
+	Message string
+}
+
+// LintError is returned when the generation of a Certificate fails lint checks.
+//
+type LintError struct {
+	Violations []LintViolation
+}
+
+// Error returns the violations as a single string.
+//
+func (lintError *LintError) Error() string {
+	return lintError.error()
+}
+
+// LintCertTemplate is called to check a Certificate (or template for one) against
+// policies enforced by common verifiers (e.g. browsers and OpenSSL). Any fields
+// not yet set in a template (e.g. SignatureAlgorithm) are not checked.
+//
+func LintCertTemplate(x509CertificateTemplate *x509.Certificate) (violations []LintViolation) {
+	return lintCertTemplate(x509CertificateTemplate)
+}
+
+// LintCert is called to check the (first) Certificate in certFile as described
+// for LintCertTemplate().
+//
+func LintCert(certFile string) (violations []LintViolation, err error) {
+	return lintCert(certFile)
+}
+
+// CertInfo summarizes a Certificate for display (e.g. by operators that would
+// otherwise resort to `openssl x509 -text`).
+//
+type CertInfo struct {
+	Subject            string        `json:"subject"`      // e.g. "O=Test Organization"
+	Issuer             string        `json:"issuer"`       // Subject of the issuing CA (or Subject if self-signed)
+	SerialNumber       string        `json:"serialNumber"` // lowercase hexadecimal
+	NotBefore          time.Time     `json:"notBefore"`
+	NotAfter           time.Time     `json:"notAfter"`
+	RemainingValidity  time.Duration `json:"remainingValidity"`  // time.Until(NotAfter) when parsed (negative if expired)
+	KeyAlgorithm       string        `json:"keyAlgorithm"`       // one of "Ed25519", "RSA", "ECDSA", or "Unknown"
+	KeySize            int           `json:"keySize"`            // in bits
+	KeyCurve           string        `json:"keyCurve"`           // e.g. "P-256" for an ECDSA key, otherwise ""
+	SignatureAlgorithm string        `json:"signatureAlgorithm"` // e.g. "Ed25519", "SHA256-RSA"
+	Fingerprint        string        `json:"fingerprint"`        // SHA-256 of the DER encoding as colon separated uppercase hexadecimal
+	DNSNames           []string      `json:"dnsNames"`
+	IPAddresses        []net.IP      `json:"ipAddresses"`
+	EmailAddresses     []string      `json:"emailAddresses"`
+	URIs               []string      `json:"uris"`
+	IsCA               bool          `json:"isCA"`
+	KeyUsage           []string      `json:"keyUsage"`    // e.g. "DigitalSignature", "CertSign"
+	ExtKeyUsage        []string      `json:"extKeyUsage"` // e.g. "ServerAuth", "ClientAuth"
+}
+
+// GetCertInfo is called to summarize the Certificate in certFile. If certFile
+// contains a chain of Certificates, the leaf is summarized. Any private key in
+// certFile is ignored.
+//
+func GetCertInfo(certFile string) (certInfo *CertInfo, err error) {
+	return getCertInfo(certFile)
+}
+
+// ParseCertInfo is called to summarize the first Certificate in pemPath. Unlike
+// GetCertInfo(), no other PEM blocks in pemPath are parsed.
+//
+func ParseCertInfo(pemPath string) (certInfo *CertInfo, err error) {
+	return parseCertInfo(pemPath)
+}
+
+// GetCertChainInfo is called to summarize each Certificate in certFile ordered
+// leaf first with each subsequent Certificate being the issuer of its predecessor.
+// Certificates in certFile not part of that chain follow in file order. Any
+// private key in certFile is ignored.
+//
+func GetCertChainInfo(certFile string) (certInfos []*CertInfo, err error) {
+	return getCertChainInfo(certFile)
+}
+
+// VerifyEndpointCert is called to confirm that the Certificate in certPath
+// chains to a CA Certificate in caCertPath and is valid for dnsNameOrIP (a DNS
+// Name or IP Address matched against its SANs) at time at (or time.Now() if at
+// is zero). Either file may be a combined PEM file. Any additional Certificates
+// in certPath are used as intermediates. Failures are reported by errors
+// wrapping ErrCertExpired, ErrCertNotYetValid (either applying to any
+// Certificate in the chain), ErrUnknownAuthority, ErrNameNotPermitted (if a
+// SAN falls outside the name constraints of a CA Certificate in the chain),
+// ErrPathLenExceeded (if the chain includes more intermediate CA Certificates
+// than one of them permits), or ErrNameMismatch.
+//
+func VerifyEndpointCert(certPath string, caCertPath string, dnsNameOrIP string, at time.Time) (err error) {
+	return verifyEndpointCert(certPath, caCertPath, dnsNameOrIP, at)
+}
+
+// VerifyCertWasIssuedByCA is called to confirm merely that the signature of the
+// (first) Certificate in leafCertPEMPath was made by the (first) CA Certificate
+// in caCertPEMPath. Unlike VerifyEndpointCert(), no chain is built and neither
+// validity periods nor names are checked. A CA Certificate lacking IsCA is
+// reported by an error wrapping ErrNotCACert and a signature not made by the CA
+// Certificate by an error wrapping ErrSignatureMismatch.
+//
+func VerifyCertWasIssuedByCA(leafCertPEMPath string, caCertPEMPath string) (err error) {
+	return verifyCertWasIssuedByCA(leafCertPEMPath, caCertPEMPath)
+}
+
+// WriteChainPEM is called to concatenate every PEM-encoded Certificate found in
+// each of certPEMPaths (in the order given, e.g. leaf, intermediates, root) into
+// outPath. Any private key in certPEMPaths is omitted. Each of certPEMPaths must
+// contain at least one Certificate. The outPath file is written as described for
+// GenCACert() with mode GeneratedFilePerm (replacing any existing file).
+//
+func WriteChainPEM(outPath string, certPEMPaths ...string) (err error) {
+	return writeChainPEM(outPath, certPEMPaths...)
+}
+
+// ReadChainPEM is called to parse every PEM-encoded Certificate in chainPEMPath
+// returning them in file order. Any private key in chainPEMPath is ignored.
+//
+func ReadChainPEM(chainPEMPath string) (x509Certificates []*x509.Certificate, err error) {
+	return loadCertChain(chainPEMPath)
+}
+
+// AppendPEM is called to append the contents of each of inPaths (in the order
+// given), each followed by a newline if it lacks a trailing one, to outPath
+// (creating it if absent). Each of inPaths must contain at least one PEM block
+// and all of them are read before outPath is modified. A newly created outPath
+// is given mode GeneratedKeyFilePerm if any of inPaths contains a private key
+// or otherwise GeneratedFilePerm. Unlike WriteChainPEM(), outPath is appended
+// to in place rather than replaced atomically.
+//
+func AppendPEM(outPath string, inPaths ...string) (err error) {
+	return appendPEM(outPath, inPaths...)
+}
+
+// DeduplicatePEM is called to rewrite pemPath omitting every CERTIFICATE block
+// whose DER encoding repeats that of an earlier one. Other PEM blocks are kept in
+// order (though any text outside of PEM blocks is discarded). The file is
+// rewritten as described for GenCACert() retaining its mode.
+//
+func DeduplicatePEM(pemPath string) (err error) {
+	return deduplicatePEM(pemPath)
+}
+
+// String renders certInfo on a single line suitable for logging.
+//
+func (certInfo *CertInfo) String() string {
+	return certInfo.string()
+}
+
+// Placeholders (referenced as "{{<placeholder>}}") that may appear in the values
+// of an IssuanceTemplate.
+//
+const (
+	TemplatePlaceholderHostname = "hostname"
+	TemplatePlaceholderFQDN     = "fqdn"
+	TemplatePlaceholderIP4      = "ip4"
+	TemplatePlaceholderCluster  = "cluster"
+	TemplatePlaceholderEnv      = "env"
+)
+
+// IssuanceTemplateSectionPrefix is the prefix of the name of a conf section
+// (e.g. "[IssuanceTemplate:prod-endpoint]") defining an IssuanceTemplate.
+// Each option of such a section is named after a field of IssuanceTemplate.
+//
+const IssuanceTemplateSectionPrefix = "IssuanceTemplate:"
+
+// IssuanceTemplate describes the Subject and SANs of an Endpoint Certificate
+// such that every host issuing from it produces an identically shaped
+// Certificate. Each value may contain placeholders (e.g. "{{fqdn}}").
+//
+type IssuanceTemplate struct {
+	Organization   []string
+	Country        []string
+	Province       []string
+	Locality       []string
+	StreetAddress  []string
+	PostalCode     []string
+	CommonName     string
+	DNSNames       []string
+	IPAddresses    []string
+	EmailAddresses []string
+	URIs           []string
+}
+
+// TemplateResolver supplies the value of each placeholder in an IssuanceTemplate.
+//
+type TemplateResolver interface {
+	ResolveTemplatePlaceholder(placeholder string) (value string, err error)
+}
+
+// ExpandedIssuance is the result of expanding an IssuanceTemplate. Resolved
+// records the value used for each placeholder referenced (e.g. for auditing).
+//
+type ExpandedIssuance struct {
+	Subject        pkix.Name
+	DNSNames       []string
+	IPAddresses    []net.IP
+	EmailAddresses []string
+	URIs           []string
+	Resolved       map[string]string
+}
+
+// NewHostTemplateResolver returns a TemplateResolver deriving the hostname, fqdn,
+// and ip4 placeholders from the local host and resolving the cluster and env
+// placeholders to the supplied values.
+//
+func NewHostTemplateResolver(cluster string, env string) (resolver TemplateResolver) {
+	return newHostTemplateResolver(cluster, env)
+}
+
+// LoadIssuanceTemplate is called to fetch the IssuanceTemplate defined in the
+// confMap section named IssuanceTemplateSectionPrefix + templateName.
+//
+func LoadIssuanceTemplate(confMap conf.ConfMap, templateName string) (issuanceTemplate *IssuanceTemplate, err error) {
+	return loadIssuanceTemplate(confMap, templateName)
+}
+
+// ExpandIssuanceTemplate is called to expand every placeholder in issuanceTemplate
+// using resolver. Any unknown or malformed placeholder fails the expansion as
+// does any resolved IPAddresses value not parsing as an IP Address.
+//
+func ExpandIssuanceTemplate(issuanceTemplate *IssuanceTemplate, resolver TemplateResolver) (expandedIssuance *ExpandedIssuance, err error) {
+	return expandIssuanceTemplate(issuanceTemplate, resolver)
+}
+
+// AppendSANs is called to add explicitly supplied SANs to those expanded
+// from an IssuanceTemplate. Duplicates are discarded.
+//
+func (expandedIssuance *ExpandedIssuance) AppendSANs(dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string) {
+	expandedIssuance.appendSANs(dnsNames, ipAddresses, emailAddresses, uris)
+}
+
+// PKICASectionName is the name of the conf section describing the CA of a
+// PKISpec. Its options are Algorithm (e.g. GenerateKeyAlgorithmEd25519), the
+// Subject options of an IssuanceTemplate section (Organization through
+// CommonName), TTL, CertFile, KeyFile, and Endpoints (listing the names of the
+// Endpoint Certificates to generate).
+//
+// PKIEndpointSectionPrefix is the prefix of the name of each conf section (e.g.
+// "[PKIEndpoint:imgr]") describing an Endpoint Certificate of a PKISpec. Its
+// options are those of the CA section (less Endpoints) plus DNSNames,
+// IPAddresses, EmailAddresses, and URIs.
+//
+const (
+	PKICASectionName         = "PKICA"
+	PKIEndpointSectionPrefix = "PKIEndpoint:"
+)
+
+// PKICertSpec describes one Certificate to be generated by GenPKI(). The
+// Certificate is generated as described for GenCACert() or GenEndpointCert()
+// with its arguments taken from the like named fields. The SANs of the CA's
+// PKICertSpec must be empty. Name identifies an Endpoint Certificate in
+// errors and the PKIResult.
+//
+type PKICertSpec struct {
+	Name                 string
+	GenerateKeyAlgorithm string
+	Subject              pkix.Name
+	DNSNames             []string
+	IPAddresses          []net.IP
+	EmailAddresses       []string
+	URIs                 []string
+	TTL                  time.Duration
+	CertFile             string
+	KeyFile              string
+}
+
+// PKISpec describes a CA and the Endpoint Certificates it issues.
+//
+type PKISpec struct {
+	CA        PKICertSpec
+	Endpoints []PKICertSpec
+}
+
+// PKIGeneratedCert reports a Certificate generated by GenPKI().
+//
+type PKIGeneratedCert struct {
+	Name     string
+	CertFile string
+	KeyFile  string
+	Info     *CertInfo
+}
+
+// PKIResult reports the Certificates generated by GenPKI() (with Endpoints in
+// the order of PKISpec.Endpoints).
+//
+type PKIResult struct {
+	CA        PKIGeneratedCert
+	Endpoints []PKIGeneratedCert
+}
+
+// LoadPKISpec is called to construct a PKISpec from the PKICASectionName
+// section of confMap and the PKIEndpointSectionPrefix section for each name in
+// its Endpoints option. Unknown options are rejected.
+//
+func LoadPKISpec(confMap conf.ConfMap) (pkiSpec *PKISpec, err error) {
+	return loadPKISpec(confMap)
+}
+
+// GenPKI is called to generate the CA and then each Endpoint Certificate
+// described by pkiSpec. Before anything is generated, pkiSpec is rejected if
+// any output path is specified for more than one Certificate or already
+// exists. If any Certificate fails to be generated, every file written by this
+// call is removed and err is returned.
+//
+func GenPKI(pkiSpec *PKISpec) (pkiResult *PKIResult, err error) {
+	return genPKI(pkiSpec)
+}
+
+// CertConfig declaratively describes a Certificate to be generated by
+// GenCertFromConfig(). If IsCA is set, a CA Certificate is generated as
+// described for GenCACert() with a path length limit of PathLen (unlimited if
+// omitted). Otherwise, an Endpoint Certificate issued by the CA in CACertFile
+// and CAKeyFile is generated as described for GenEndpointCert(). KeyAlgorithm
+// is a GenerateKeyAlgorithm value (e.g. GenerateKeyAlgorithmEd25519), TTL is a
+// duration string (e.g. "720h"), KeyUsage and ExtKeyUsage take the names
+// reported in CertInfo (e.g. "DigitalSignature", "ServerAuth"), and the
+// remaining fields correspond to the like named arguments and CertOptions. A
+// relative file path is taken relative to the directory of the config file.
+//
+type CertConfig struct {
+	KeyAlgorithm          string            `json:"keyAlgorithm" yaml:"keyAlgorithm"`
+	Subject               CertConfigSubject `json:"subject" yaml:"subject"`
+	TTL                   string            `json:"ttl" yaml:"ttl"`
+	DNSNames              []string          `json:"dnsNames,omitempty" yaml:"dnsNames,omitempty"`
+	IPAddresses           []string          `json:"ipAddresses,omitempty" yaml:"ipAddresses,omitempty"`
+	EmailAddresses        []string          `json:"emailAddresses,omitempty" yaml:"emailAddresses,omitempty"`
+	URIs                  []string          `json:"uris,omitempty" yaml:"uris,omitempty"`
+	IsCA                  bool              `json:"isCA,omitempty" yaml:"isCA,omitempty"`
+	PathLen               *int              `json:"pathLen,omitempty" yaml:"pathLen,omitempty"`
+	KeyUsage              []string          `json:"keyUsage,omitempty" yaml:"keyUsage,omitempty"`
+	ExtKeyUsage           []string          `json:"extKeyUsage,omitempty" yaml:"extKeyUsage,omitempty"`
+	OCSPResponderURL      string            `json:"ocspResponderURL,omitempty" yaml:"ocspResponderURL,omitempty"`
+	IssuingCertificateURL string            `json:"issuingCertificateURL,omitempty" yaml:"issuingCertificateURL,omitempty"`
+	CRLDistributionPoints []string          `json:"crlDistributionPoints,omitempty" yaml:"crlDistributionPoints,omitempty"`
+	ClampToCAExpiry       bool              `json:"clampToCAExpiry,omitempty" yaml:"clampToCAExpiry,omitempty"`
+	MustStaple            bool              `json:"mustStaple,omitempty" yaml:"mustStaple,omitempty"`
+	Overwrite             bool              `json:"overwrite,omitempty" yaml:"overwrite,omitempty"`
+	CACertFile            string            `json:"caCertFile,omitempty" yaml:"caCertFile,omitempty"`
+	CAKeyFile             string            `json:"caKeyFile,omitempty" yaml:"caKeyFile,omitempty"`
+	CertFile              string            `json:"certFile" yaml:"certFile"`
+	KeyFile               string            `json:"keyFile" yaml:"keyFile"`
+}
+
+// CertConfigSubject is the Subject of a CertConfig.
+//
+type CertConfigSubject struct {
+	Organization  []string `json:"organization,omitempty" yaml:"organization,omitempty"`
+	Country       []string `json:"country,omitempty" yaml:"country,omitempty"`
+	Province      []string `json:"province,omitempty" yaml:"province,omitempty"`
+	Locality      []string `json:"locality,omitempty" yaml:"locality,omitempty"`
+	StreetAddress []string `json:"streetAddress,omitempty" yaml:"streetAddress,omitempty"`
+	PostalCode    []string `json:"postalCode,omitempty" yaml:"postalCode,omitempty"`
+	CommonName    string   `json:"commonName,omitempty" yaml:"commonName,omitempty"`
+}
+
+// GenCertFromConfig is called to generate the Certificate described by the
+// CertConfig in configPath. A configPath ending in ".json" is parsed as JSON
+// while one ending in ".yaml" or ".yml" is parsed as YAML. Unknown fields are
+// rejected.
+//
+func GenCertFromConfig(configPath string) (err error) {
+	return genCertFromConfig(configPath)
+}
+
+// SPIFFEBundleOptions specifies the optional spiffe_sequence and
+// spiffe_refresh_hint (rounded down to whole seconds) members of a SPIFFE trust
+// bundle emitted by ExportSPIFFEBundleWithOptions(). Zero values are omitted.
+//
+type SPIFFEBundleOptions struct {
+	Sequence    uint64
+	RefreshHint time.Duration
+}
+
+// ExportSPIFFEBundle is called to write to w the SPIFFE trust bundle (a JWK Set
+// with an "x509-svid" key, carrying its Certificate in "x5c", for each CA) for
+// trustDomain (e.g. "example.org") trusting every Certificate in caCertPaths.
+// Each Certificate must be a CA and any SPIFFE ID in its URI SANs must belong
+// to trustDomain. Any private key in caCertPaths is ignored.
+//
+func ExportSPIFFEBundle(caCertPaths []string, trustDomain string, w io.Writer) (err error) {
+	return exportSPIFFEBundle(caCertPaths, trustDomain, w, nil)
+}
+
+// ExportSPIFFEBundleWithOptions is called to write a SPIFFE trust bundle just
+// like ExportSPIFFEBundle() but including the members specified by options.
+//
+func ExportSPIFFEBundleWithOptions(caCertPaths []string, trustDomain string, w io.Writer, options *SPIFFEBundleOptions) (err error) {
+	return exportSPIFFEBundle(caCertPaths, trustDomain, w, options)
+}
+
+// ImportSPIFFEBundle is called to read a SPIFFE trust bundle from r returning
+// an x509.CertPool containing the Certificate of each "x509-svid" key (other
+// keys, e.g. "jwt-svid", are ignored). The bundle is rejected if any such key
+// lacks precisely one Certificate, the Certificate is not a CA, or the key
+// does not match the Certificate's public key.
+//
+func ImportSPIFFEBundle(r io.Reader) (certPool *x509.CertPool, err error) {
+	return importSPIFFEBundle(r)
+}
+
+// GenCertRequest describes one Endpoint Certificate to be generated by
+// GenEndpointCerts(). The fields correspond to the like named arguments of
+// (*CA).GenEndpointCertWithOptions().
+//
+type GenCertRequest struct {
+	GenerateKeyAlgorithm string
+	Subject              pkix.Name
+	DNSNames             []string
+	IPAddresses          []net.IP
+	EmailAddresses       []string
+	URIs                 []string
+	TTL                  time.Duration
+	CertFile             string
+	KeyFile              string
+	Options              *CertOptions
+}
+
+// GenCertResult reports the outcome of one GenCertRequest. If Err is nil,
+// SerialNumber is that of the generated Certificate.
+//
+type GenCertResult struct {
+	CertFile     string
+	KeyFile      string
+	SerialNumber *big.Int
+	Err          error
+}
+
+// GenEndpointCerts is called to generate the Endpoint Certificate described by
+// each of requests, all issued by ca, using up to parallelism concurrent workers
+// (or runtime.NumCPU() if parallelism is not positive). The returned results
+// correspond, in order, to requests. A failed request (including one naming an
+// output path also named by an earlier request) is reported by its Err without
+// affecting the others. The SerialNumbers of the batch are all distinct.
+//
+func GenEndpointCerts(ca *CA, requests []GenCertRequest, parallelism int) (results []GenCertResult, err error) {
+	return genEndpointCerts(ca, requests, parallelism)
+}
+
+// ExtractSKI is called to return the Subject Key Identifier of the (first)
+// Certificate in certPEMPath. Generated Certificates carry a Subject Key
+// Identifier computed per method (1) of RFC 5280 section 4.2.1.2 and, if issued
+// by a CA, an Authority Key Identifier equal to that of the CA. An error is
+// returned if the Certificate has no Subject Key Identifier.
+//
+func ExtractSKI(certPEMPath string) (ski []byte, err error) {
+	return extractSKI(certPEMPath)
+}
+
+// Platforms whose system trust store InstallCAToSystemTrust() and
+// RemoveCAFromSystemTrust() support. On linux, TrustPlatformDebian (via
+// update-ca-certificates) or TrustPlatformRHEL (via update-ca-trust) is detected
+// by which tool is installed.
+//
+const (
+	TrustPlatformDebian  = "debian"
+	TrustPlatformRHEL    = "rhel"
+	TrustPlatformDarwin  = "darwin"
+	TrustPlatformWindows = "windows"
+)
+
+// TrustRecordSuffix is appended to caCertPath to name the file in which
+// InstallCAToSystemTrust() records what it installed so that
+// RemoveCAFromSystemTrust() removes precisely that.
+//
+const TrustRecordSuffix = ".trust"
+
+// Errors returned (wrapped) by InstallCAToSystemTrust() and
+// RemoveCAFromSystemTrust(). ErrTrustNotConfirmed indicates TrustOptions.Confirm
+// was not set while ErrInsufficientPrivileges indicates a trust store command
+// failed for lack of privileges (the error suggesting how to re-run).
+//
+var (
+	ErrTrustNotConfirmed      = errors.New("modifying the system trust store requires confirmation")
+	ErrInsufficientPrivileges = errors.New("insufficient privileges to modify the system trust store")
+)
+
+// TrustOptions specifies the behavior of InstallCAToSystemTrust() and
+// RemoveCAFromSystemTrust().
+//
+type TrustOptions struct {
+	// Confirm must be true to modify the system trust store.
+	//
+	Confirm bool
+
+	// Platform, if non-empty, overrides detection of the running system's
+	// trust store. It must be one of the TrustPlatform* values.
+	//
+	Platform string
+
+	// Runner, if non-nil, replaces running each trust store command (e.g.
+	// "update-ca-certificates") via os/exec. It returns the combined output of
+	// the command named name given args.
+	//
+	Runner func(name string, args ...string) (output []byte, err error)
+
+	// LookPath, if non-nil, replaces exec.LookPath() when detecting which linux
+	// trust store tool is installed.
+	//
+	LookPath func(file string) (path string, err error)
+}
+
+// InstallCAToSystemTrust is called to add the (first) CA Certificate in
+// caCertPath to the system trust store (so that e.g. curl and browsers trust
+// Certificates it issues) and record what was installed in
+// caCertPath+TrustRecordSuffix. If that CA Certificate is already recorded as
+// installed, nothing is done. If a different (e.g. since regenerated) CA
+// Certificate was recorded as installed from caCertPath, it is first removed.
+// Modifying the system trust store typically requires running as root (or as
+// Administrator on windows).
+//
+func InstallCAToSystemTrust(caCertPath string, options *TrustOptions) (err error) {
+	return installCAToSystemTrust(caCertPath, options)
+}
+
+// RemoveCAFromSystemTrust is called to remove from the system trust store
+// precisely what InstallCAToSystemTrust() recorded installing from caCertPath
+// (and then the record itself). If nothing is recorded, nothing is done.
+//
+func RemoveCAFromSystemTrust(caCertPath string, options *TrustOptions) (err error) {
+	return removeCAFromSystemTrust(caCertPath, options)
+}
+
+// CertNeedsRenewal is called to report whether the remaining validity of the
+// (first) Certificate in certPath is less than threshold (along with its
+// NotAfter). A missing certPath needs renewal (and is reported with a zero
+// NotAfter).
+//
+func CertNeedsRenewal(certPath string, threshold time.Duration) (needsRenewal bool, notAfter time.Time, err error) {
+	return certNeedsRenewal(certPath, threshold, time.Now())
+}
+
+// RenewCACert is called to extend the validity of the self-signed CA Certificate
+// in caCertPath (whose private key is in caKeyPath) without invalidating the
+// Certificates it has already issued. The renewed CA Certificate, written to
+// outputCertPath, has a new SerialNumber and a validity lasting newTTL from
+// time.Now() but is otherwise identical (including its Subject, Subject Key
+// Identifier, key, and extensions). Should the private key in caKeyPath not be
+// that of the CA Certificate, an error wrapping ErrKeyCertMismatch is returned
+// and nothing is written.
+//
+// If caCertPath and caKeyPath are the same (or outputCertPath is caKeyPath), the
+// private key is also written to outputCertPath (as for GenCACert() given the
+// same certFile and keyFile). Otherwise only the renewed CA Certificate is
+// written. Either way, outputCertPath (which may be caCertPath) is replaced
+// atomically.
+//
+func RenewCACert(caCertPath string, caKeyPath string, newTTL time.Duration, outputCertPath string) (err error) {
+	return renewCACert(caCertPath, caKeyPath, newTTL, outputCertPath)
+}
+
+// CrossSignCert is called to re-issue the (first) Certificate in
+// existingCertPEMPath signed by the CA whose Certificate is in newCACertPEMPath
+// and private key in newCAKeyPEMPath (e.g. during a migration to a new root CA).
+// The cross-signed Certificate written to outCertPEMPath (which must differ from
+// each of the other paths) has a new random SerialNumber but the same Subject,
+// public key, Subject Key Identifier, SANs, KeyUsage, ExtKeyUsage, CA basic
+// constraints, and validity (NotBefore and NotAfter) as the existing one. Should
+// it outlive the new CA Certificate (beyond CAExpiryClampTolerance), an error
+// wrapping ErrExceedsCAExpiry is returned. No private key is needed or written.
+//
+func CrossSignCert(existingCertPEMPath string, newCACertPEMPath string, newCAKeyPEMPath string, outCertPEMPath string) (err error) {
+	return crossSignCert(existingCertPEMPath, newCACertPEMPath, newCAKeyPEMPath, outCertPEMPath)
+}
+
+// DefaultRenewalCheckInterval is the interval at which a RenewalWatcher with a
+// zero CheckInterval checks whether its Certificate needs renewal.
+//
+const DefaultRenewalCheckInterval = time.Minute
+
+// RenewalWatcherOptions supplies optional tuning of a RenewalWatcher.
+//
+// The watched Certificate is re-issued once its remaining validity falls below
+// Threshold (or it is missing). It is checked upon creation and then every
+// CheckInterval (or DefaultRenewalCheckInterval if not positive) or, if Tick is
+// non-nil, upon each receipt from Tick instead. Now, if non-nil, replaces
+// time.Now() both when checking and (unless the GenCertRequest's Options set
+// their own) as the Now of the re-issued Certificate.
+//
+// By default, the re-issued Certificate is for the existing private key in the
+// GenCertRequest's KeyFile (or CertFile if KeyFile is empty), a new one being
+// generated only if none is found. If NewKey is set, a new private key is always
+// generated.
+//
+// If OnRenewal is non-nil, it is called (from the watching goroutine) following
+// each attempted renewal with a nil Err upon success. If the Certificate fails
+// to be checked (e.g. holding bad PEM content), OnRenewal is called with that
+// error and no renewal is attempted.
+//
+type RenewalWatcherOptions struct {
+	Threshold     time.Duration
+	CheckInterval time.Duration
+	Tick          <-chan time.Time
+	Now           func() time.Time
+	NewKey        bool
+	OnRenewal     func(result GenCertResult)
+}
+
+// RenewalWatcher re-issues, via a CA, the Endpoint Certificate described by a
+// GenCertRequest whenever it nears expiry. Re-issued files atomically replace
+// the previous ones as described for GenCACert().
+//
+type RenewalWatcher struct {
+	ca       *CA
+	request  GenCertRequest
+	options  RenewalWatcherOptions
+	stopOnce sync.Once
+	stopChan chan struct{}
+	stopWG   sync.WaitGroup
+}
+
+// NewRenewalWatcher is called to launch a goroutine that watches the Certificate
+// in request.CertFile and re-issues it, via ca, as described by request whenever
+// it needs renewal. The goroutine runs until either ctx is done or Close() is
+// called.
+//
+func NewRenewalWatcher(ctx context.Context, ca *CA, request GenCertRequest, options *RenewalWatcherOptions) (renewalWatcher *RenewalWatcher, err error) {
+	return newRenewalWatcher(ctx, ca, request, options)
+}
+
+// Close is called to stop the watching goroutine (if not already stopped via
+// its ctx) awaiting the completion of any renewal underway.
+//
+func (renewalWatcher *RenewalWatcher) Close() {
+	renewalWatcher.close()
+}
+
+// HardenedTLSMinVersion is the lowest TLS version HardenedTLSConfig() permits.
+//
+const HardenedTLSMinVersion = tls.VersionTLS12
+
+// HardenedTLSConfig is called to return a clone of base (or of an empty
+// tls.Config if base is nil) accepting only TLS versions from minVersion (or
+// HardenedTLSMinVersion if minVersion is lower, including zero) onward and, for
+// TLS 1.2, only those of allowedCipherSuites (or, if allowedCipherSuites is nil,
+// all of RecommendedCipherSuites()) that are also returned by
+// RecommendedCipherSuites(). A MaxVersion of base below minVersion is cleared.
+// The TLS 1.3 cipher suites are not configurable and so are unaffected.
+//
+func HardenedTLSConfig(base *tls.Config, minVersion uint16, allowedCipherSuites []uint16) *tls.Config {
+	return hardenedTLSConfig(base, minVersion, allowedCipherSuites)
+}
+
+// RecommendedCipherSuites is called to return the IDs of the cipher suites of
+// tls.CipherSuites() having no known vulnerabilities (in particular, excluding
+// any RC4, 3DES, export, or NULL cipher suite).
+//
+func RecommendedCipherSuites() []uint16 {
+	return recommendedCipherSuites()
+}
+
+// NewTLSListener is called to return a net.Listener on network and addr (as
+// per tls.Listen()) accepting TLS connections presenting the Certificate (along
+// with any chain Certificates following it) in certPemFilePath and its private
+// key in keyPemFilePath. For a combined certPemFilePath, keyPemFilePath may be
+// either certPemFilePath or empty. The tls.Config is that of HardenedTLSConfig().
+//
+func NewTLSListener(network string, addr string, certPemFilePath string, keyPemFilePath string) (netListener net.Listener, err error) {
+	return newTLSListener(network, addr, certPemFilePath, keyPemFilePath)
+}
+
+// NewTLSDialConfig is called to return a tls.Config (as per HardenedTLSConfig())
+// for a client trusting each of the Certificates in caCertPemFilePath. Should
+// caCertPemFilePath hold no Certificate, an *ErrBadPEM is returned. The server
+// name is left unset so that tls.Dial() derives it from the address dialed.
+//
+func NewTLSDialConfig(caCertPemFilePath string) (tlsConfig *tls.Config, err error) {
+	return newTLSDialConfig(caCertPemFilePath)
+}
+
+// Dial is called to connect to addr on network (as per tls.Dial()) using the
+// tls.Config of NewTLSDialConfig(caCertPemFilePath) and giving up after timeout
+// (if non-zero) for the connection and TLS handshake to complete.
+//
+func Dial(network string, addr string, caCertPemFilePath string, timeout time.Duration) (tlsConn *tls.Conn, err error) {
+	return dial(network, addr, caCertPemFilePath, timeout)
+}
+
+// CertStore abstracts where PEM-encoded Certificates and private keys are kept
+// (e.g. a secrets manager or database rather than the filesystem). Each id names
+// a Certificate and its private key which are read and written separately. A
+// ReadCert() or ReadKey() of an id for which nothing was written must return an
+// error wrapping os.ErrNotExist. A CertStore must be safe for concurrent use by
+// multiple goroutines.
+//
+type CertStore interface {
+	ReadCert(id string) (certPEM []byte, err error)
+	ReadKey(id string) (keyPEM []byte, err error)
+	WriteCert(id string, certPEM []byte) (err error)
+	WriteKey(id string, keyPEM []byte) (err error)
+}
+
+// CertIDLister is optionally implemented by a CertStore able to enumerate the
+// ids for which it holds a Certificate (as is required by NewAutoRotateManager()).
+// Both FileCertStore and InMemoryCertStore implement it.
+//
+type CertIDLister interface {
+	ListCertIDs() (ids []string, err error)
+}
+
+// KeyDeleter is optionally implemented by a CertStore able to delete the private
+// key of an id (so that a private key written for an id that had none may be
+// rolled back should writing its Certificate then fail). Both FileCertStore and
+// InMemoryCertStore implement it.
+//
+type KeyDeleter interface {
+	DeleteKey(id string) (err error)
+}
+
+const (
+	FileCertStoreCertSuffix = "_cert.pem"
+	FileCertStoreKeySuffix  = "_key.pem"
+)
+
+// FileCertStore is a CertStore keeping the Certificate and private key of each
+// id in files named id+FileCertStoreCertSuffix and id+FileCertStoreKeySuffix in
+// a directory. Each is replaced atomically with the same modes (GeneratedFilePerm
+// and GeneratedKeyFilePerm respectively) as the files written by GenCACert()
+// and GenEndpointCert(). An id containing a path separator or ".." is rejected
+// with ErrInvalidCertID.
+//
+type FileCertStore struct {
+	dir string
+}
+
+// NewFileCertStore is called to return a FileCertStore keeping its files in dir.
+//
+func NewFileCertStore(dir string) (fileCertStore *FileCertStore) {
+	return newFileCertStore(dir)
+}
+
+// ReadCert returns the Certificate PEM of id.
+//
+func (fileCertStore *FileCertStore) ReadCert(id string) (certPEM []byte, err error) {
+	return fileCertStore.readCert(id)
+}
+
+// ListCertIDs returns (in lexical order) the ids having a Certificate file.
+//
+func (fileCertStore *FileCertStore) ListCertIDs() (ids []string, err error) {
+	return fileCertStore.listCertIDs()
+}
+
+// ReadKey returns the private key PEM of id.
+//
+func (fileCertStore *FileCertStore) ReadKey(id string) (keyPEM []byte, err error) {
+	return fileCertStore.readKey(id)
+}
+
+// WriteCert replaces the Certificate PEM of id.
+//
+func (fileCertStore *FileCertStore) WriteCert(id string, certPEM []byte) (err error) {
+	return fileCertStore.writeCert(id, certPEM)
+}
+
+// WriteKey replaces the private key PEM of id.
+//
+func (fileCertStore *FileCertStore) WriteKey(id string, keyPEM []byte) (err error) {
+	return fileCertStore.writeKey(id, keyPEM)
+}
+
+// DeleteKey removes the private key file of id.
+//
+func (fileCertStore *FileCertStore) DeleteKey(id string) (err error) {
+	return fileCertStore.deleteKey(id)
+}
+
+// InMemoryCertStore is a CertStore holding everything written to it in memory
+// (e.g. for testing). It is safe for concurrent use by multiple goroutines.
+//
+type InMemoryCertStore struct {
+	sync.Mutex
+	certs map[string][]byte
+	keys  map[string][]byte
+}
+
+// NewInMemoryCertStore is called to return an empty InMemoryCertStore.
+//
+func NewInMemoryCertStore() (inMemoryCertStore *InMemoryCertStore) {
+	return newInMemoryCertStore()
+}
+
+// ReadCert returns a copy of the Certificate PEM of id.
+//
+func (inMemoryCertStore *InMemoryCertStore) ReadCert(id string) (certPEM []byte, err error) {
+	return inMemoryCertStore.readCert(id)
+}
+
+// ListCertIDs returns (in lexical order) the ids having a Certificate.
+//
+func (inMemoryCertStore *InMemoryCertStore) ListCertIDs() (ids []string, err error) {
+	return inMemoryCertStore.listCertIDs()
+}
+
+// ReadKey returns a copy of the private key PEM of id.
+//
+func (inMemoryCertStore *InMemoryCertStore) ReadKey(id string) (keyPEM []byte, err error) {
+	return inMemoryCertStore.readKey(id)
+}
+
+// WriteCert replaces the Certificate PEM of id with a copy of certPEM.
+//
+func (inMemoryCertStore *InMemoryCertStore) WriteCert(id string, certPEM []byte) (err error) {
+	return inMemoryCertStore.writeCert(id, certPEM)
+}
+
+// WriteKey replaces the private key PEM of id with a copy of keyPEM.
+//
+func (inMemoryCertStore *InMemoryCertStore) WriteKey(id string, keyPEM []byte) (err error) {
+	return inMemoryCertStore.writeKey(id, keyPEM)
+}
+
+// DeleteKey removes the private key PEM of id.
+//
+func (inMemoryCertStore *InMemoryCertStore) DeleteKey(id string) (err error) {
+	return inMemoryCertStore.deleteKey(id)
+}
+
+// GenCACertToStore is called to generate a CA Certificate just like
+// GenCACertWithOptions() but writing it and its private key to store under id
+// rather than to files. Where an existing CA private key file would be refused,
+// so is an existing private key of id (unless options.Overwrite is set). The
+// Lock, SkipIfValidFor, and ExistingKeyFile options are not supported.
+//
+func GenCACertToStore(store CertStore, generateKeyAlgorithm string, subject pkix.Name, ttl time.Duration, id string, options *CertOptions) (err error) {
+	return genCACertToStore(store, generateKeyAlgorithm, subject, ttl, id, options)
+}
+
+// GenEndpointCertToStore is called to generate an Endpoint Certificate just like
+// GenEndpointCertWithOptions() but signed by the CA held by store under caID and
+// writing it and its private key to store under id rather than to files. Should
+// store hold nothing for caID, an error wrapping ErrCANotFound is returned. The
+// Lock, SkipIfValidFor, and ExistingKeyFile options are not supported.
+//
+func GenEndpointCertToStore(store CertStore, generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, caID string, id string, options *CertOptions) (err error) {
+	return genEndpointCertToStore(store, generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, caID, id, options)
+}
+
+// AutoRotateManager periodically scans a CertStore renewing (via
+// RenewEndpointCert()) each Endpoint Certificate issued by its CA whose remaining
+// validity has fallen below a warning threshold. CA Certificates, Certificates
+// issued by other CAs, and the CA's own id are left alone. An AutoRotateManager
+// is safe for concurrent use by multiple goroutines.
+//
+type AutoRotateManager struct {
+	sync.Mutex
+	store            CertStore
+	lister           CertIDLister
+	caCertID         string
+	caKeyID          string
+	warningThreshold time.Duration
+	checkInterval    time.Duration
+	onRenewed        func(id string)
+	lastScanErr      error
+	stopChan         chan struct{}
+	stopOnce         sync.Once
+	stopWG           sync.WaitGroup
+}
+
+// NewAutoRotateManager is called to launch a goroutine that, immediately and
+// then every checkInterval, scans store (which must implement CertIDLister)
+// renewing each Endpoint Certificate signed by the CA whose Certificate is held
+// under caCertID and private key under caKeyID (which may be identical) that
+// expires within warningThreshold. Each renewed Certificate is written back to
+// store under the same id following which, if non-nil, onRenewed is called
+// (from the scanning goroutine) with that id.
+//
+func NewAutoRotateManager(store CertStore, caCertID string, caKeyID string, warningThreshold time.Duration, checkInterval time.Duration, onRenewed func(id string)) (autoRotateManager *AutoRotateManager, err error) {
+	return newAutoRotateManager(store, caCertID, caKeyID, warningThreshold, checkInterval, onRenewed)
+}
+
+// LastScanErr returns the first error encountered by the most recent scan (or
+// nil if it encountered none). An id failing to renew does not stop the scan.
+//
+func (autoRotateManager *AutoRotateManager) LastScanErr() (err error) {
+	return autoRotateManager.getLastScanErr()
+}
+
+// Stop is called to stop scanning. It waits for any scan in progress to finish
+// and may safely be called more than once.
+//
+func (autoRotateManager *AutoRotateManager) Stop() {
+	autoRotateManager.stop()
+}
+
+// RenewEndpointCert is called to re-issue the Endpoint Certificate held by store
+// under id, signed by the CA whose Certificate is held under caCertID and private
+// key under caKeyID, writing it back under id. The new Certificate is for the
+// same private key, Subject, SANs, usage, revocation URLs, and other extensions
+// as the old one and is valid for the same duration starting now (or at
+// options.NotBefore). If the old Certificate was not issued by the CA, an error
+// wrapping ErrUnknownAuthority is returned. Other options (e.g. Rand or Now)
+// apply as for GenEndpointCertToStore().
+//
+func RenewEndpointCert(store CertStore, caCertID string, caKeyID string, id string, options *CertOptions) (err error) {
+	return renewEndpointCert(store, caCertID, caKeyID, id, options)
+}
+
+// ReadExtension is called to return the value and criticality of the extension
+// identified by oid (e.g. as added via CertOptions.Extensions) in the first
+// Certificate in certPEMPath. Should it have no such extension, an error
+// wrapping ErrExtensionNotFound is returned.
+//
+func ReadExtension(certPEMPath string, oid asn1.ObjectIdentifier) (value []byte, critical bool, err error) {
+	return readExtension(certPEMPath, oid)
+}
+
+// Fingerprint is called to return the SHA-256 fingerprint of the first
+// Certificate in certPath in the form reported as CertInfo.Fingerprint (i.e.
+// colon separated uppercase hexadecimal).
+//
+func Fingerprint(certPath string) (fingerprintString string, err error) {
+	return fingerprint(certPath)
+}
+
+// BuildPinnedClientTLSConfig is called to return a tls.Config (as per
+// HardenedTLSConfig()) for a client accepting only a server whose leaf
+// Certificate has one of fingerprints (each a SHA-256 in hexadecimal of either
+// case, optionally colon separated, as returned by Fingerprint()) and is valid
+// now. No CA is consulted so the chain, if any, and the server name are not
+// verified. Pinning several fingerprints allows a new Certificate to be
+// rolled out before the old one is retired. A server failing the check fails
+// the handshake with an error wrapping ErrPinMismatch, ErrCertExpired, or
+// ErrCertNotYetValid.
+//
+func BuildPinnedClientTLSConfig(fingerprints []string) (tlsConfig *tls.Config, err error) {
+	return buildPinnedClientTLSConfig(fingerprints)
+}
+
+// IssuanceRecord.Kind values.
+//
+const (
+	IssuanceKindCA              = "CA"
+	IssuanceKindCARenewal       = "CARenewal"
+	IssuanceKindCrossSign       = "CrossSign"
+	IssuanceKindEndpoint        = "Endpoint"
+	IssuanceKindEndpointRenewal = "EndpointRenewal"
+	IssuanceKindSelfSigned      = "SelfSigned"
+)
+
+// IssuanceDestinationInMemory is the IssuanceRecord.Destination of a
+// Certificate written to an InMemoryCertStore.
+//
+const IssuanceDestinationInMemory = "in-memory"
+
+// IssuanceRecord describes a Certificate just issued (e.g. for an audit log)
+// as passed to the issuance callback (see SetIssuanceCallback()). Time is when
+// it was reported and Kind an IssuanceKind* value (an Endpoint Certificate
+// re-issued by a RenewalWatcher, an AutoRotateManager, or RenewEndpointCert()
+// being reported as IssuanceKindEndpointRenewal). Destination is the path of
+// the Certificate file written or, for a CertStore, the path within a
+// FileCertStore, IssuanceDestinationInMemory for an InMemoryCertStore, or the
+// id within any other CertStore.
+//
+type IssuanceRecord struct {
+	Time           time.Time
+	Kind           string
+	Issuer         pkix.Name
+	Subject        pkix.Name
+	DNSNames       []string
+	IPAddresses    []net.IP
+	EmailAddresses []string
+	URIs           []string
+	SerialNumber   *big.Int
+	NotBefore      time.Time
+	NotAfter       time.Time
+	KeyAlgorithm   x509.PublicKeyAlgorithm
+	Destination    string
+}
+
+// SetIssuanceCallback is called to set (or, if nil, clear) the callback passed
+// an IssuanceRecord following each Certificate successfully generated and
+// written by this package (i.e. by any GenCACert*(), GenEndpointCert*(),
+// GenSelfSignedCert*(), or *ToStore() function, by renewal, automatic rotation,
+// RenewCACert(), or CrossSignCert()). It is not called when generation fails
+// or is skipped (see CertOptions.SkipIfValidFor). As callback is called from
+// the goroutine generating each Certificate, it must be safe for concurrent use
+// when Certificates are generated concurrently (e.g. by GenEndpointCerts()). It
+// may itself generate Certificates (each in turn reported to it). Should
+// callback panic, the panic is recovered and the generation returns an error
+// wrapping ErrIssuanceCallbackPanic (though the Certificate has been written).
 //
-func GenEndpointCert(generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, ttl time.Duration, caCertFile string, caKeyFile string, endpointCertFile string, endpointKeyFile string) (err error) {
-	return genEndpointCert(generateKeyAlgorithm, subject, dnsNames, ipAddresses, ttl, caCertFile, caKeyFile, endpointCertFile, endpointKeyFile)
+func SetIssuanceCallback(callback func(issuanceRecord IssuanceRecord)) {
+	setIssuanceCallback(callback)
 }
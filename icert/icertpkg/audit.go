@@ -0,0 +1,115 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	issuanceCallback     func(issuanceRecord IssuanceRecord)
+	issuanceCallbackLock sync.Mutex
+)
+
+func setIssuanceCallback(callback func(issuanceRecord IssuanceRecord)) {
+	issuanceCallbackLock.Lock()
+	issuanceCallback = callback
+	issuanceCallbackLock.Unlock()
+}
+
+// reportIssuance passes an IssuanceRecord describing the just written (DER
+// encoded) x509Certificate of kind to the issuance callback (if set). The
+// callback is fetched under issuanceCallbackLock but called without it held so
+// that it may itself issue a Certificate and concurrent issuances needn't wait
+// on each other's callback. A panicking callback is recovered with an error
+// wrapping ErrIssuanceCallbackPanic returned instead.
+//
+func reportIssuance(kind string, x509Certificate []byte, destination string) (err error) {
+	var (
+		callback              func(issuanceRecord IssuanceRecord)
+		issuanceRecord        IssuanceRecord
+		parsedX509Certificate *x509.Certificate
+	)
+
+	issuanceCallbackLock.Lock()
+	callback = issuanceCallback
+	issuanceCallbackLock.Unlock()
+
+	if nil == callback {
+		return
+	}
+
+	parsedX509Certificate, err = x509.ParseCertificate(x509Certificate)
+	if nil != err {
+		return
+	}
+
+	issuanceRecord = IssuanceRecord{
+		Time:           time.Now(),
+		Kind:           kind,
+		Issuer:         parsedX509Certificate.Issuer,
+		Subject:        parsedX509Certificate.Subject,
+		DNSNames:       parsedX509Certificate.DNSNames,
+		IPAddresses:    parsedX509Certificate.IPAddresses,
+		EmailAddresses: parsedX509Certificate.EmailAddresses,
+		URIs:           make([]string, 0, len(parsedX509Certificate.URIs)),
+		SerialNumber:   parsedX509Certificate.SerialNumber,
+		NotBefore:      parsedX509Certificate.NotBefore,
+		NotAfter:       parsedX509Certificate.NotAfter,
+		KeyAlgorithm:   parsedX509Certificate.PublicKeyAlgorithm,
+		Destination:    destination,
+	}
+
+	for _, uri := range parsedX509Certificate.URIs {
+		issuanceRecord.URIs = append(issuanceRecord.URIs, uri.String())
+	}
+
+	defer func() {
+		var (
+			panicValue interface{}
+		)
+
+		panicValue = recover()
+		if nil != panicValue {
+			err = fmt.Errorf("%w: %v", ErrIssuanceCallbackPanic, panicValue)
+		}
+	}()
+
+	callback(issuanceRecord)
+
+	return
+}
+
+// issuanceDestination returns the IssuanceRecord.Destination of a Certificate
+// written (see writeCertAndKey()) to certFile.
+//
+func (options *CertOptions) issuanceDestination(certFile string) string {
+	switch certStore := options.certStore.(type) {
+	case nil:
+		return certFile
+	case *FileCertStore:
+		certPath, err := certStore.certPath(certFile)
+		if nil != err {
+			return certFile
+		}
+		return certPath
+	case *InMemoryCertStore:
+		return IssuanceDestinationInMemory
+	default:
+		return certFile
+	}
+}
+
+// issuanceKindOr returns options.issuanceKind or, if not set, kind.
+//
+func (options *CertOptions) issuanceKindOr(kind string) string {
+	if "" == options.issuanceKind {
+		return kind
+	}
+
+	return options.issuanceKind
+}
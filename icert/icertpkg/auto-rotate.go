@@ -0,0 +1,267 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+func newAutoRotateManager(store CertStore, caCertID string, caKeyID string, warningThreshold time.Duration, checkInterval time.Duration, onRenewed func(id string)) (autoRotateManager *AutoRotateManager, err error) {
+	var (
+		lister CertIDLister
+		ok     bool
+	)
+
+	if nil == store {
+		err = fmt.Errorf("store must not be nil")
+		return
+	}
+
+	lister, ok = store.(CertIDLister)
+	if !ok {
+		err = fmt.Errorf("store (%T) must implement CertIDLister", store)
+		return
+	}
+
+	if warningThreshold <= time.Duration(0) {
+		err = fmt.Errorf("warningThreshold (%v) must be positive", warningThreshold)
+		return
+	}
+	if checkInterval <= time.Duration(0) {
+		err = fmt.Errorf("checkInterval (%v) must be positive", checkInterval)
+		return
+	}
+
+	autoRotateManager = &AutoRotateManager{
+		store:            store,
+		lister:           lister,
+		caCertID:         caCertID,
+		caKeyID:          caKeyID,
+		warningThreshold: warningThreshold,
+		checkInterval:    checkInterval,
+		onRenewed:        onRenewed,
+		stopChan:         make(chan struct{}),
+	}
+
+	autoRotateManager.stopWG.Add(1)
+
+	go autoRotateManager.rotator()
+
+	return
+}
+
+func (autoRotateManager *AutoRotateManager) getLastScanErr() (err error) {
+	autoRotateManager.Lock()
+	err = autoRotateManager.lastScanErr
+	autoRotateManager.Unlock()
+
+	return
+}
+
+func (autoRotateManager *AutoRotateManager) stop() {
+	autoRotateManager.stopOnce.Do(func() { close(autoRotateManager.stopChan) })
+	autoRotateManager.stopWG.Wait()
+}
+
+func (autoRotateManager *AutoRotateManager) rotator() {
+	var (
+		ticker *time.Ticker
+	)
+
+	defer autoRotateManager.stopWG.Done()
+
+	ticker = time.NewTicker(autoRotateManager.checkInterval)
+	defer ticker.Stop()
+
+	// Scan immediately so that a nearly expired Certificate need not await the
+	// first tick
+
+	autoRotateManager.scan()
+
+	for {
+		select {
+		case <-autoRotateManager.stopChan:
+			return
+		case <-ticker.C:
+			autoRotateManager.scan()
+		}
+	}
+}
+
+// scan renews each Endpoint Certificate in the store, issued by the CA, whose
+// remaining validity has fallen below warningThreshold. The first error
+// encountered (if any) becomes lastScanErr though the scan continues with the
+// remaining ids.
+//
+func (autoRotateManager *AutoRotateManager) scan() {
+	var (
+		ca              *CA
+		certPEM         []byte
+		err             error
+		id              string
+		ids             []string
+		scanErr         error
+		timeNow         time.Time
+		x509Certificate *x509.Certificate
+	)
+
+	defer func() {
+		autoRotateManager.Lock()
+		autoRotateManager.lastScanErr = scanErr
+		autoRotateManager.Unlock()
+	}()
+
+	ids, scanErr = autoRotateManager.lister.ListCertIDs()
+	if nil != scanErr {
+		return
+	}
+
+	ca, scanErr = readCAFromStore(autoRotateManager.store, autoRotateManager.caCertID, autoRotateManager.caKeyID)
+	if nil != scanErr {
+		return
+	}
+
+	timeNow = time.Now()
+
+	for _, id = range ids {
+		if autoRotateManager.caCertID == id {
+			continue
+		}
+
+		certPEM, err = autoRotateManager.store.ReadCert(id)
+		if nil == err {
+			x509Certificate, err = parseFirstCert(certPEM, id)
+		}
+		if nil != err {
+			if nil == scanErr {
+				scanErr = err
+			}
+			continue
+		}
+
+		// Leave alone other CAs and Certificates issued by some other CA
+
+		if x509Certificate.IsCA || (nil != x509Certificate.CheckSignatureFrom(ca.x509Certificate)) {
+			continue
+		}
+
+		if x509Certificate.NotAfter.Sub(timeNow) >= autoRotateManager.warningThreshold {
+			continue
+		}
+
+		err = ca.renewEndpointCertInStore(autoRotateManager.store, id, nil)
+		if nil != err {
+			if nil == scanErr {
+				scanErr = fmt.Errorf("renewing \"%s\": %w", id, err)
+			}
+			continue
+		}
+
+		if nil != autoRotateManager.onRenewed {
+			autoRotateManager.onRenewed(id)
+		}
+	}
+}
+
+func renewEndpointCert(store CertStore, caCertID string, caKeyID string, id string, options *CertOptions) (err error) {
+	var (
+		ca *CA
+	)
+
+	if nil == store {
+		err = fmt.Errorf("store must not be nil")
+		return
+	}
+
+	ca, err = readCAFromStore(store, caCertID, caKeyID)
+	if nil != err {
+		return
+	}
+
+	err = ca.renewEndpointCertInStore(store, id, options)
+
+	return
+}
+
+// renewEndpointCertInStore re-issues (via this CA) the Endpoint Certificate held
+// by store under id for the same private key, Subject, SANs, usage, revocation
+// URLs, and other extensions and for the same duration as its validity.
+//
+func (ca *CA) renewEndpointCertInStore(store CertStore, id string, options *CertOptions) (err error) {
+	var (
+		certPEM         []byte
+		keyPEM          []byte
+		oldOptions      CertOptions
+		storeOptions    *CertOptions
+		tlsCertificate  tls.Certificate
+		uris            []string
+		x509Certificate *x509.Certificate
+	)
+
+	certPEM, err = store.ReadCert(id)
+	if nil != err {
+		return
+	}
+	keyPEM, err = store.ReadKey(id)
+	if nil != err {
+		return
+	}
+
+	tlsCertificate, err = parseKeyPair(certPEM, id, keyPEM, id)
+	if nil != err {
+		err = fmt.Errorf("\"%s\" in CertStore: %w", id, err)
+		return
+	}
+
+	x509Certificate = tlsCertificate.Leaf
+
+	if x509Certificate.IsCA {
+		err = fmt.Errorf("\"%s\" in CertStore is a CA Certificate... renew it via RenewCACert()", id)
+		return
+	}
+
+	err = x509Certificate.CheckSignatureFrom(ca.x509Certificate)
+	if nil != err {
+		err = fmt.Errorf("%w: \"%s\" in CertStore was not issued by CA \"%s\" (%v)", ErrUnknownAuthority, id, ca.x509Certificate.Subject, err)
+		return
+	}
+
+	if nil != options {
+		oldOptions = *options
+	}
+
+	oldOptions.ExistingKey = tlsCertificate.PrivateKey
+	oldOptions.Usage = CertUsageOptions{
+		KeyUsage:           x509Certificate.KeyUsage,
+		ExtKeyUsage:        x509Certificate.ExtKeyUsage,
+		UnknownExtKeyUsage: x509Certificate.UnknownExtKeyUsage,
+	}
+	if (nil == oldOptions.Usage.ExtKeyUsage) && (0 != len(oldOptions.Usage.UnknownExtKeyUsage)) {
+		oldOptions.Usage.ExtKeyUsage = []x509.ExtKeyUsage{} // Only the unknown ExtKeyUsage OIDs... not the defaults
+	}
+	oldOptions.OCSPServerURLs = x509Certificate.OCSPServer
+	oldOptions.IssuingCertificateURLs = x509Certificate.IssuingCertificateURL
+	oldOptions.renewedRevocationURLs = true
+	oldOptions.CRLDistributionPoints = x509Certificate.CRLDistributionPoints
+	oldOptions.ExtraExtensions = endpointRenewalExtraExtensions(x509Certificate)
+	oldOptions.issuanceKind = IssuanceKindEndpointRenewal
+
+	storeOptions, err = newStoreCertOptions(store, id, &oldOptions)
+	if nil != err {
+		return
+	}
+
+	uris = make([]string, 0, len(x509Certificate.URIs))
+	for _, uri := range x509Certificate.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	err = ca.genEndpointCert(context.Background(), "", x509Certificate.Subject, x509Certificate.DNSNames, x509Certificate.IPAddresses, x509Certificate.EmailAddresses, uris, x509Certificate.NotAfter.Sub(x509Certificate.NotBefore), id, id, storeOptions)
+
+	return
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+func genEndpointCerts(ca *CA, requests []GenCertRequest, parallelism int) (results []GenCertResult, err error) {
+	var (
+		ok            bool
+		path          string
+		pathIndex     int
+		paths         map[string]int
+		requestChan   chan int
+		serialNumber  *big.Int
+		serialNumbers []*big.Int
+		serialsSeen   map[string]struct{}
+		workerWG      sync.WaitGroup
+	)
+
+	if nil == ca {
+		err = fmt.Errorf("ca must not be nil")
+		return
+	}
+
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	if parallelism > len(requests) {
+		parallelism = len(requests)
+	}
+
+	results = make([]GenCertResult, len(requests))
+	paths = make(map[string]int)
+
+	for i := range requests {
+		results[i].CertFile = requests[i].CertFile
+		results[i].KeyFile = requests[i].KeyFile
+
+		for _, path = range []string{requests[i].CertFile, requests[i].KeyFile} {
+			if "" == path {
+				continue
+			}
+			path = filepath.Clean(path)
+			pathIndex, ok = paths[path]
+			if ok && (pathIndex != i) {
+				results[i].Err = fmt.Errorf("output path \"%s\" also specified by requests[%d]", path, pathIndex)
+				break
+			}
+			paths[path] = i
+		}
+	}
+
+	// Assign distinct SerialNumbers up front so that concurrent issuance need
+	// not coordinate
+
+	serialNumbers = make([]*big.Int, len(requests))
+	serialsSeen = make(map[string]struct{}, len(requests))
+
+	for i := range requests {
+		for {
+			serialNumber, err = genSerialNumber(rand.Reader)
+			if nil != err {
+				results = nil
+				return
+			}
+			_, ok = serialsSeen[serialNumber.String()]
+			if !ok {
+				break
+			}
+		}
+		serialsSeen[serialNumber.String()] = struct{}{}
+		serialNumbers[i] = serialNumber
+	}
+
+	requestChan = make(chan int, len(requests))
+	for i := range requests {
+		if nil == results[i].Err {
+			requestChan <- i
+		}
+	}
+	close(requestChan)
+
+	for w := 0; w < parallelism; w++ {
+		workerWG.Add(1)
+		go func() {
+			var (
+				options CertOptions
+				request *GenCertRequest
+			)
+
+			defer workerWG.Done()
+
+			for i := range requestChan {
+				request = &requests[i]
+
+				if nil == request.Options {
+					options = CertOptions{}
+				} else {
+					options = *request.Options
+				}
+				options.serialNumber = serialNumbers[i]
+
+				results[i].Err = ca.genEndpointCert(context.Background(), request.GenerateKeyAlgorithm, request.Subject, request.DNSNames, request.IPAddresses, request.EmailAddresses, request.URIs, request.TTL, request.CertFile, request.KeyFile, &options)
+				if nil == results[i].Err {
+					results[i].SerialNumber = serialNumbers[i]
+				}
+			}
+		}()
+	}
+
+	workerWG.Wait()
+
+	return
+}
@@ -0,0 +1,372 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+var keyUsageNames = []struct {
+	keyUsage x509.KeyUsage
+	name     string
+}{
+	{x509.KeyUsageDigitalSignature, "DigitalSignature"},
+	{x509.KeyUsageContentCommitment, "ContentCommitment"},
+	{x509.KeyUsageKeyEncipherment, "KeyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "DataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "KeyAgreement"},
+	{x509.KeyUsageCertSign, "CertSign"},
+	{x509.KeyUsageCRLSign, "CRLSign"},
+	{x509.KeyUsageEncipherOnly, "EncipherOnly"},
+	{x509.KeyUsageDecipherOnly, "DecipherOnly"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                            "Any",
+	x509.ExtKeyUsageServerAuth:                     "ServerAuth",
+	x509.ExtKeyUsageClientAuth:                     "ClientAuth",
+	x509.ExtKeyUsageCodeSigning:                    "CodeSigning",
+	x509.ExtKeyUsageEmailProtection:                "EmailProtection",
+	x509.ExtKeyUsageIPSECEndSystem:                 "IPSECEndSystem",
+	x509.ExtKeyUsageIPSECTunnel:                    "IPSECTunnel",
+	x509.ExtKeyUsageIPSECUser:                      "IPSECUser",
+	x509.ExtKeyUsageTimeStamping:                   "TimeStamping",
+	x509.ExtKeyUsageOCSPSigning:                    "OCSPSigning",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "MicrosoftServerGatedCrypto",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      "NetscapeServerGatedCrypto",
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "MicrosoftCommercialCodeSigning",
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "MicrosoftKernelCodeSigning",
+}
+
+func getCertInfo(certFile string) (certInfo *CertInfo, err error) {
+	var (
+		certInfos []*CertInfo
+	)
+
+	certInfos, err = getCertChainInfo(certFile)
+	if nil != err {
+		return
+	}
+
+	certInfo = certInfos[0]
+
+	return
+}
+
+func parseCertInfo(pemPath string) (certInfo *CertInfo, err error) {
+	var (
+		x509Certificate *x509.Certificate
+	)
+
+	x509Certificate, err = loadFirstCert(pemPath)
+	if nil != err {
+		return
+	}
+
+	certInfo = newCertInfo(x509Certificate, time.Now())
+
+	return
+}
+
+func getCertChainInfo(certFile string) (certInfos []*CertInfo, err error) {
+	var (
+		timeNow          time.Time
+		x509Certificate  *x509.Certificate
+		x509Certificates []*x509.Certificate
+	)
+
+	x509Certificates, err = loadCertChain(certFile)
+	if nil != err {
+		return
+	}
+
+	timeNow = time.Now()
+
+	certInfos = make([]*CertInfo, 0, len(x509Certificates))
+
+	for _, x509Certificate = range orderCertChain(x509Certificates) {
+		certInfos = append(certInfos, newCertInfo(x509Certificate, timeNow))
+	}
+
+	return
+}
+
+// loadFirstCert returns the first Certificate in certFile skipping any other
+// PEM blocks preceding it. Subsequent PEM blocks are not parsed.
+//
+func loadFirstCert(certFile string) (x509Certificate *x509.Certificate, err error) {
+	var (
+		certPEM []byte
+	)
+
+	certPEM, err = ioutil.ReadFile(certFile)
+	if nil != err {
+		return
+	}
+
+	x509Certificate, err = parseFirstCert(certPEM, certFile)
+
+	return
+}
+
+// parseFirstCert is loadFirstCert() for the PEM content certPEM read from
+// certFile.
+//
+func parseFirstCert(certPEM []byte, certFile string) (x509Certificate *x509.Certificate, err error) {
+	var (
+		blockIndex int
+		pemBlock   *pem.Block
+	)
+
+	for blockIndex = 0; ; blockIndex++ {
+		pemBlock, certPEM = pem.Decode(certPEM)
+		if nil == pemBlock {
+			err = &ErrBadPEM{Path: certFile, BlockIndex: -1, Err: errors.New("no CERTIFICATE found")}
+			return
+		}
+		if "CERTIFICATE" == pemBlock.Type {
+			break
+		}
+	}
+
+	x509Certificate, err = x509.ParseCertificate(pemBlock.Bytes)
+	if nil != err {
+		err = &ErrBadPEM{Path: certFile, BlockIndex: blockIndex, Err: fmt.Errorf("unable to parse CERTIFICATE: %v", err)}
+		return
+	}
+
+	return
+}
+
+// loadCertChain returns every Certificate in certFile in file order skipping
+// any other PEM blocks (e.g. a private key).
+//
+func loadCertChain(certFile string) (x509Certificates []*x509.Certificate, err error) {
+	var (
+		certPEM []byte
+	)
+
+	certPEM, err = ioutil.ReadFile(certFile)
+	if nil != err {
+		return
+	}
+
+	x509Certificates, err = parseCertChain(certPEM, certFile)
+
+	return
+}
+
+// parseCertChain is loadCertChain() for the PEM content certPEM read from
+// certFile.
+//
+func parseCertChain(certPEM []byte, certFile string) (x509Certificates []*x509.Certificate, err error) {
+	var (
+		blockIndex      int
+		pemBlock        *pem.Block
+		x509Certificate *x509.Certificate
+	)
+
+	x509Certificates = make([]*x509.Certificate, 0, 1)
+
+	for blockIndex = 0; ; blockIndex++ {
+		pemBlock, certPEM = pem.Decode(certPEM)
+		if nil == pemBlock {
+			break
+		}
+		if "CERTIFICATE" != pemBlock.Type {
+			continue
+		}
+
+		x509Certificate, err = x509.ParseCertificate(pemBlock.Bytes)
+		if nil != err {
+			x509Certificates = nil
+			err = &ErrBadPEM{Path: certFile, BlockIndex: blockIndex, Err: fmt.Errorf("unable to parse CERTIFICATE: %v", err)}
+			return
+		}
+
+		x509Certificates = append(x509Certificates, x509Certificate)
+	}
+
+	if 0 == len(x509Certificates) {
+		x509Certificates = nil
+		err = &ErrBadPEM{Path: certFile, BlockIndex: -1, Err: errors.New("no CERTIFICATE found")}
+		return
+	}
+
+	err = nil
+	return
+}
+
+// orderCertChain orders x509Certificates leaf first with each subsequent
+// Certificate being the issuer of its predecessor. The leaf is the first
+// Certificate that issued none of the others. Any Certificates not part of
+// that chain follow in their original order.
+//
+func orderCertChain(x509Certificates []*x509.Certificate) (orderedX509Certificates []*x509.Certificate) {
+	var (
+		issuedOther bool
+		leafIndex   int
+		otherIndex  int
+	)
+
+	for leafIndex = range x509Certificates {
+		issuedOther = false
+		for otherIndex = range x509Certificates {
+			if (otherIndex != leafIndex) && bytes.Equal(x509Certificates[otherIndex].RawIssuer, x509Certificates[leafIndex].RawSubject) {
+				issuedOther = true
+				break
+			}
+		}
+		if !issuedOther {
+			break
+		}
+	}
+	if issuedOther {
+		leafIndex = 0
+	}
+
+	orderedX509Certificates = orderCertChainFrom(x509Certificates, leafIndex)
+
+	return
+}
+
+// orderCertChainFrom is orderCertChain() with x509Certificates[leafIndex] taken
+// as the leaf.
+//
+func orderCertChainFrom(x509Certificates []*x509.Certificate, leafIndex int) (orderedX509Certificates []*x509.Certificate) {
+	var (
+		candidateIndex int
+		found          bool
+		used           []bool
+	)
+
+	orderedX509Certificates = make([]*x509.Certificate, 0, len(x509Certificates))
+	used = make([]bool, len(x509Certificates))
+
+	orderedX509Certificates = append(orderedX509Certificates, x509Certificates[leafIndex])
+	used[leafIndex] = true
+
+	for {
+		found = false
+		for candidateIndex = range x509Certificates {
+			if !used[candidateIndex] && bytes.Equal(x509Certificates[candidateIndex].RawSubject, orderedX509Certificates[len(orderedX509Certificates)-1].RawIssuer) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		orderedX509Certificates = append(orderedX509Certificates, x509Certificates[candidateIndex])
+		used[candidateIndex] = true
+	}
+
+	for candidateIndex = range x509Certificates {
+		if !used[candidateIndex] {
+			orderedX509Certificates = append(orderedX509Certificates, x509Certificates[candidateIndex])
+		}
+	}
+
+	return
+}
+
+func newCertInfo(x509Certificate *x509.Certificate, timeNow time.Time) (certInfo *CertInfo) {
+	var (
+		extKeyUsage x509.ExtKeyUsage
+		name        string
+		ok          bool
+	)
+
+	certInfo = &CertInfo{
+		Subject:            x509Certificate.Subject.String(),
+		Issuer:             x509Certificate.Issuer.String(),
+		SerialNumber:       x509Certificate.SerialNumber.Text(16),
+		NotBefore:          x509Certificate.NotBefore,
+		NotAfter:           x509Certificate.NotAfter,
+		RemainingValidity:  x509Certificate.NotAfter.Sub(timeNow),
+		SignatureAlgorithm: x509Certificate.SignatureAlgorithm.String(),
+		DNSNames:           x509Certificate.DNSNames,
+		IPAddresses:        x509Certificate.IPAddresses,
+		EmailAddresses:     x509Certificate.EmailAddresses,
+		URIs:               make([]string, 0, len(x509Certificate.URIs)),
+		IsCA:               x509Certificate.IsCA,
+		KeyUsage:           make([]string, 0),
+		ExtKeyUsage:        make([]string, 0, len(x509Certificate.ExtKeyUsage)),
+	}
+
+	switch publicKey := x509Certificate.PublicKey.(type) {
+	case ed25519.PublicKey:
+		certInfo.KeyAlgorithm = "Ed25519"
+		certInfo.KeySize = 8 * len(publicKey)
+	case *rsa.PublicKey:
+		certInfo.KeyAlgorithm = "RSA"
+		certInfo.KeySize = publicKey.N.BitLen()
+	case *ecdsa.PublicKey:
+		certInfo.KeyAlgorithm = "ECDSA"
+		certInfo.KeySize = publicKey.Curve.Params().BitSize
+		certInfo.KeyCurve = publicKey.Curve.Params().Name
+	default:
+		certInfo.KeyAlgorithm = "Unknown"
+	}
+
+	certInfo.Fingerprint = certFingerprint(x509Certificate)
+
+	for _, uri := range x509Certificate.URIs {
+		certInfo.URIs = append(certInfo.URIs, uri.String())
+	}
+
+	for _, keyUsageName := range keyUsageNames {
+		if 0 != (x509Certificate.KeyUsage & keyUsageName.keyUsage) {
+			certInfo.KeyUsage = append(certInfo.KeyUsage, keyUsageName.name)
+		}
+	}
+
+	for _, extKeyUsage = range x509Certificate.ExtKeyUsage {
+		name, ok = extKeyUsageNames[extKeyUsage]
+		if !ok {
+			name = fmt.Sprintf("ExtKeyUsage(%d)", extKeyUsage)
+		}
+		certInfo.ExtKeyUsage = append(certInfo.ExtKeyUsage, name)
+	}
+
+	return
+}
+
+func (certInfo *CertInfo) string() string {
+	var (
+		key string
+	)
+
+	key = fmt.Sprintf("%s/%d", certInfo.KeyAlgorithm, certInfo.KeySize)
+	if "" != certInfo.KeyCurve {
+		key += "/" + certInfo.KeyCurve
+	}
+
+	return fmt.Sprintf("subject=%q issuer=%q serial=%s notBefore=%s notAfter=%s remaining=%v key=%s signature=%s fingerprint=%s dns=%v ip=%v email=%v uri=%v isCA=%v keyUsage=%v extKeyUsage=%v",
+		certInfo.Subject,
+		certInfo.Issuer,
+		certInfo.SerialNumber,
+		certInfo.NotBefore.UTC().Format(time.RFC3339),
+		certInfo.NotAfter.UTC().Format(time.RFC3339),
+		certInfo.RemainingValidity.Truncate(time.Second),
+		key,
+		certInfo.SignatureAlgorithm,
+		certInfo.Fingerprint,
+		certInfo.DNSNames,
+		certInfo.IPAddresses,
+		certInfo.EmailAddresses,
+		certInfo.URIs,
+		certInfo.IsCA,
+		certInfo.KeyUsage,
+		certInfo.ExtKeyUsage)
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+)
+
+func newCertManager(certFile string, keyFile string, reloadInterval time.Duration) (certManager *CertManager, err error) {
+	var (
+		tlsCertificate tls.Certificate
+	)
+
+	if reloadInterval <= time.Duration(0) {
+		err = fmt.Errorf("reloadInterval (%v) must be positive", reloadInterval)
+		return
+	}
+
+	tlsCertificate, err = loadKeyPair(certFile, keyFile)
+	if nil != err {
+		return
+	}
+
+	certManager = &CertManager{
+		certFile:       certFile,
+		keyFile:        keyFile,
+		reloadInterval: reloadInterval,
+		tlsCertificate: &tlsCertificate,
+		stopChan:       make(chan struct{}),
+	}
+
+	certManager.stopWG.Add(1)
+
+	go certManager.reloader()
+
+	return
+}
+
+func (certManager *CertManager) getCertificate(clientHelloInfo *tls.ClientHelloInfo) (tlsCertificate *tls.Certificate, err error) {
+	certManager.RLock()
+	tlsCertificate = certManager.tlsCertificate
+	certManager.RUnlock()
+
+	err = nil
+	return
+}
+
+func (certManager *CertManager) getLastReloadErr() (err error) {
+	certManager.RLock()
+	err = certManager.lastReloadErr
+	certManager.RUnlock()
+
+	return
+}
+
+func (certManager *CertManager) stop() {
+	certManager.stopOnce.Do(func() { close(certManager.stopChan) })
+	certManager.stopWG.Wait()
+}
+
+func (certManager *CertManager) reloader() {
+	var (
+		err            error
+		ticker         *time.Ticker
+		tlsCertificate tls.Certificate
+	)
+
+	defer certManager.stopWG.Done()
+
+	ticker = time.NewTicker(certManager.reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-certManager.stopChan:
+			return
+		case <-ticker.C:
+			tlsCertificate, err = loadKeyPair(certManager.certFile, certManager.keyFile)
+
+			certManager.Lock()
+			if nil == err {
+				certManager.tlsCertificate = &tlsCertificate
+			}
+			certManager.lastReloadErr = err
+			certManager.Unlock()
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"time"
+)
+
+func newCertReloader(certPath string, keyPath string, options *CertReloaderOptions) (certReloader *CertReloader, err error) {
+	certReloader = &CertReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+		stopChan: make(chan struct{}),
+	}
+
+	if nil != options {
+		certReloader.options = *options
+	}
+	if time.Duration(0) == certReloader.options.PollInterval {
+		certReloader.options.PollInterval = CertReloaderDefaultPollInterval
+	}
+
+	err = certReloader.reload()
+	if nil != err {
+		certReloader = nil
+		return
+	}
+
+	if certReloader.options.PollInterval > time.Duration(0) {
+		certReloader.stopWG.Add(1)
+		go certReloader.poller()
+	}
+
+	return
+}
+
+func (certReloader *CertReloader) getCertificate() (tlsCertificate *tls.Certificate, err error) {
+	certReloader.RLock()
+	tlsCertificate = certReloader.tlsCertificate
+	certReloader.RUnlock()
+
+	err = nil
+	return
+}
+
+func (certReloader *CertReloader) modTimes() (certModTime time.Time, keyModTime time.Time, err error) {
+	var (
+		fileInfo os.FileInfo
+	)
+
+	fileInfo, err = os.Stat(certReloader.certPath)
+	if nil != err {
+		return
+	}
+	certModTime = fileInfo.ModTime()
+
+	fileInfo, err = os.Stat(certReloader.keyPath)
+	if nil != err {
+		return
+	}
+	keyModTime = fileInfo.ModTime()
+
+	return
+}
+
+func (certReloader *CertReloader) reload() (err error) {
+	var (
+		certModTime    time.Time
+		keyModTime     time.Time
+		tlsCertificate tls.Certificate
+	)
+
+	// Capture the modification times before reading so that a change racing
+	// with the read is picked up by the next poll
+
+	certModTime, keyModTime, err = certReloader.modTimes()
+	if nil != err {
+		return
+	}
+
+	tlsCertificate, err = loadKeyPair(certReloader.certPath, certReloader.keyPath)
+	if nil != err {
+		return
+	}
+
+	tlsCertificate.Leaf, err = x509.ParseCertificate(tlsCertificate.Certificate[0])
+	if nil != err {
+		return
+	}
+
+	certReloader.Lock()
+	certReloader.tlsCertificate = &tlsCertificate
+	certReloader.certModTime = certModTime
+	certReloader.keyModTime = keyModTime
+	certReloader.Unlock()
+
+	return
+}
+
+func (certReloader *CertReloader) modified() (modified bool) {
+	var (
+		certModTime time.Time
+		err         error
+		keyModTime  time.Time
+	)
+
+	certModTime, keyModTime, err = certReloader.modTimes()
+	if nil != err {
+		// Let the reload attempt surface the error
+
+		modified = true
+		return
+	}
+
+	certReloader.RLock()
+	modified = !certModTime.Equal(certReloader.certModTime) || !keyModTime.Equal(certReloader.keyModTime)
+	certReloader.RUnlock()
+
+	return
+}
+
+func (certReloader *CertReloader) poller() {
+	var (
+		err    error
+		ticker *time.Ticker
+	)
+
+	defer certReloader.stopWG.Done()
+
+	ticker = time.NewTicker(certReloader.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-certReloader.stopChan:
+			return
+		case <-ticker.C:
+			if certReloader.modified() {
+				err = certReloader.reload()
+				if (nil != err) && (nil != certReloader.options.OnReloadError) {
+					certReloader.options.OnReloadError(err)
+				}
+			}
+		}
+	}
+}
+
+func (certReloader *CertReloader) stop() {
+	certReloader.stopOnce.Do(func() {
+		close(certReloader.stopChan)
+		certReloader.stopWG.Wait()
+	})
+}
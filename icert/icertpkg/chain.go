@@ -0,0 +1,200 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+func writeChainPEM(outPath string, certPEMPaths ...string) (err error) {
+	var (
+		certFound    bool
+		certPEM      []byte
+		certPEMPath  string
+		chainPEM     []byte
+		pemBlock     *pem.Block
+		tmpChainPath string
+	)
+
+	if 0 == len(certPEMPaths) {
+		err = fmt.Errorf("no certPEMPaths specified")
+		return
+	}
+
+	chainPEM = make([]byte, 0)
+
+	for _, certPEMPath = range certPEMPaths {
+		certPEM, err = ioutil.ReadFile(certPEMPath)
+		if nil != err {
+			return
+		}
+
+		certFound = false
+
+		for {
+			pemBlock, certPEM = pem.Decode(certPEM)
+			if nil == pemBlock {
+				break
+			}
+			if "CERTIFICATE" != pemBlock.Type {
+				continue
+			}
+
+			chainPEM = append(chainPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pemBlock.Bytes})...)
+			certFound = true
+		}
+
+		if !certFound {
+			err = &ErrBadPEM{Path: certPEMPath, BlockIndex: -1, Err: errors.New("no CERTIFICATE found")}
+			return
+		}
+	}
+
+	tmpChainPath, err = writeTmpFile(outPath, chainPEM, GeneratedFilePerm)
+	if nil != err {
+		return
+	}
+
+	err = installTmpFile(tmpChainPath, outPath, true)
+	if nil != err {
+		_ = os.Remove(tmpChainPath)
+	}
+
+	return
+}
+
+func appendPEM(outPath string, inPaths ...string) (err error) {
+	var (
+		appendedPEM []byte
+		blockFound  bool
+		inPath      string
+		inPEM       []byte
+		outFile     *os.File
+		pemBlock    *pem.Block
+		perm        os.FileMode
+		remainder   []byte
+	)
+
+	// Read (and check) every inPath before modifying outPath
+
+	appendedPEM = make([]byte, 0)
+	perm = GeneratedFilePerm
+
+	for _, inPath = range inPaths {
+		inPEM, err = ioutil.ReadFile(inPath)
+		if nil != err {
+			return
+		}
+
+		blockFound = false
+
+		for remainder = inPEM; ; {
+			pemBlock, remainder = pem.Decode(remainder)
+			if nil == pemBlock {
+				break
+			}
+			blockFound = true
+			if strings.HasSuffix(pemBlock.Type, "PRIVATE KEY") {
+				perm = GeneratedKeyFilePerm
+			}
+		}
+
+		if !blockFound {
+			err = &ErrBadPEM{Path: inPath, BlockIndex: -1, Err: errors.New("no PEM block found")}
+			return
+		}
+
+		appendedPEM = append(appendedPEM, inPEM...)
+		if !bytes.HasSuffix(inPEM, []byte("\n")) {
+			appendedPEM = append(appendedPEM, '\n')
+		}
+	}
+
+	outFile, err = os.OpenFile(outPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if nil != err {
+		return
+	}
+
+	_, err = outFile.Write(appendedPEM)
+	if nil != err {
+		_ = outFile.Close()
+		return
+	}
+
+	err = outFile.Sync()
+	if nil != err {
+		_ = outFile.Close()
+		return
+	}
+
+	err = outFile.Close()
+
+	return
+}
+
+func deduplicatePEM(pemPath string) (err error) {
+	var (
+		blockIndex      int
+		certDERs        map[string]struct{}
+		deduplicatedPEM []byte
+		duplicate       bool
+		fileInfo        os.FileInfo
+		inPEM           []byte
+		pemBlock        *pem.Block
+		tmpDeduplicated string
+	)
+
+	fileInfo, err = os.Stat(pemPath)
+	if nil != err {
+		return
+	}
+
+	inPEM, err = ioutil.ReadFile(pemPath)
+	if nil != err {
+		return
+	}
+
+	certDERs = make(map[string]struct{})
+	deduplicatedPEM = make([]byte, 0, len(inPEM))
+
+	for blockIndex = 0; ; blockIndex++ {
+		pemBlock, inPEM = pem.Decode(inPEM)
+		if nil == pemBlock {
+			break
+		}
+
+		if "CERTIFICATE" == pemBlock.Type {
+			_, duplicate = certDERs[string(pemBlock.Bytes)]
+			if duplicate {
+				continue
+			}
+			certDERs[string(pemBlock.Bytes)] = struct{}{}
+		}
+
+		deduplicatedPEM = append(deduplicatedPEM, pem.EncodeToMemory(pemBlock)...)
+	}
+
+	if 0 == blockIndex {
+		err = &ErrBadPEM{Path: pemPath, BlockIndex: -1, Err: errors.New("no PEM block found")}
+		return
+	}
+
+	tmpDeduplicated, err = writeTmpFile(pemPath, deduplicatedPEM, fileInfo.Mode().Perm())
+	if nil != err {
+		return
+	}
+
+	err = installTmpFile(tmpDeduplicated, pemPath, true)
+	if nil != err {
+		_ = os.Remove(tmpDeduplicated)
+	}
+
+	return
+}
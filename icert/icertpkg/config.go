@@ -0,0 +1,267 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func genCertFromConfig(configPath string) (err error) {
+	var (
+		certConfig *CertConfig
+	)
+
+	certConfig, err = loadCertConfig(configPath)
+	if nil != err {
+		return
+	}
+
+	err = certConfig.gen(filepath.Dir(configPath))
+	if nil != err {
+		err = fmt.Errorf("config \"%s\": %w", configPath, err)
+	}
+
+	return
+}
+
+// loadCertConfig reads configPath, unmarshaling it as JSON or YAML according
+// to its extension. Unknown fields are rejected.
+//
+func loadCertConfig(configPath string) (certConfig *CertConfig, err error) {
+	var (
+		configBuf     []byte
+		configDecoder *json.Decoder
+	)
+
+	configBuf, err = ioutil.ReadFile(configPath)
+	if nil != err {
+		return
+	}
+
+	certConfig = &CertConfig{}
+
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".json":
+		configDecoder = json.NewDecoder(bytes.NewReader(configBuf))
+		configDecoder.DisallowUnknownFields()
+		err = configDecoder.Decode(certConfig)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(configBuf, certConfig)
+	default:
+		err = fmt.Errorf("extension of \"%s\" not supported... must be one of \".json\", \".yaml\", or \".yml\"", configPath)
+		certConfig = nil
+		return
+	}
+	if nil != err {
+		certConfig = nil
+		err = fmt.Errorf("unable to parse config \"%s\": %v", configPath, err)
+		return
+	}
+
+	return
+}
+
+// gen generates the Certificate described by certConfig resolving any relative
+// file paths against configDir.
+//
+func (certConfig *CertConfig) gen(configDir string) (err error) {
+	var (
+		certFile    string
+		ipAddresses []net.IP
+		keyFile     string
+		options     *CertOptions
+		subject     pkix.Name
+		ttl         time.Duration
+	)
+
+	if "" == certConfig.TTL {
+		err = fmt.Errorf("ttl must be specified")
+		return
+	}
+	ttl, err = time.ParseDuration(certConfig.TTL)
+	if nil != err {
+		err = fmt.Errorf("ttl \"%s\" invalid: %v", certConfig.TTL, err)
+		return
+	}
+
+	if ("" == certConfig.CertFile) || ("" == certConfig.KeyFile) {
+		err = fmt.Errorf("both certFile and keyFile must be specified")
+		return
+	}
+
+	certFile = resolveConfigPath(configDir, certConfig.CertFile)
+	keyFile = resolveConfigPath(configDir, certConfig.KeyFile)
+
+	subject = pkix.Name{
+		Organization:  certConfig.Subject.Organization,
+		Country:       certConfig.Subject.Country,
+		Province:      certConfig.Subject.Province,
+		Locality:      certConfig.Subject.Locality,
+		StreetAddress: certConfig.Subject.StreetAddress,
+		PostalCode:    certConfig.Subject.PostalCode,
+		CommonName:    certConfig.Subject.CommonName,
+	}
+
+	options = &CertOptions{
+		Overwrite:             certConfig.Overwrite,
+		ClampToCAExpiry:       certConfig.ClampToCAExpiry,
+		MustStaple:            certConfig.MustStaple,
+		OCSPResponderURL:      certConfig.OCSPResponderURL,
+		IssuingCertificateURL: certConfig.IssuingCertificateURL,
+		CRLDistributionPoints: certConfig.CRLDistributionPoints,
+	}
+
+	options.Usage.KeyUsage, err = parseKeyUsageNames(certConfig.KeyUsage)
+	if nil != err {
+		return
+	}
+	options.Usage.ExtKeyUsage, err = parseExtKeyUsageNames(certConfig.ExtKeyUsage)
+	if nil != err {
+		return
+	}
+
+	if certConfig.IsCA {
+		if (0 != len(certConfig.DNSNames)) || (0 != len(certConfig.IPAddresses)) || (0 != len(certConfig.EmailAddresses)) || (0 != len(certConfig.URIs)) {
+			err = fmt.Errorf("CA may not specify SANs")
+			return
+		}
+		if ("" != certConfig.CACertFile) || ("" != certConfig.CAKeyFile) {
+			err = fmt.Errorf("CA may not specify caCertFile or caKeyFile")
+			return
+		}
+
+		switch {
+		case nil == certConfig.PathLen:
+			options.Constraints.MaxPathLen = -1
+		case *certConfig.PathLen < 0:
+			err = fmt.Errorf("pathLen %d invalid... must not be negative", *certConfig.PathLen)
+			return
+		default:
+			options.Constraints.MaxPathLen = *certConfig.PathLen
+			options.Constraints.MaxPathLenZero = (0 == *certConfig.PathLen)
+		}
+
+		err = genCACert(context.Background(), certConfig.KeyAlgorithm, subject, ttl, certFile, keyFile, options)
+		return
+	}
+
+	if nil != certConfig.PathLen {
+		err = fmt.Errorf("pathLen may only be specified for a CA")
+		return
+	}
+	if ("" == certConfig.CACertFile) || ("" == certConfig.CAKeyFile) {
+		err = fmt.Errorf("both caCertFile and caKeyFile must be specified unless isCA")
+		return
+	}
+
+	ipAddresses, err = parseIPAddresses(certConfig.IPAddresses)
+	if nil != err {
+		return
+	}
+
+	err = genEndpointCert(context.Background(), certConfig.KeyAlgorithm, subject, certConfig.DNSNames, ipAddresses, certConfig.EmailAddresses, certConfig.URIs, ttl, resolveConfigPath(configDir, certConfig.CACertFile), resolveConfigPath(configDir, certConfig.CAKeyFile), certFile, keyFile, options)
+
+	return
+}
+
+// resolveConfigPath returns path resolved against configDir unless already
+// absolute.
+//
+func resolveConfigPath(configDir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(configDir, path)
+}
+
+// parseKeyUsageNames returns the x509.KeyUsage named by names (each as
+// reported in CertInfo.KeyUsage). No names yields a zero (default) KeyUsage.
+//
+func parseKeyUsageNames(names []string) (keyUsage x509.KeyUsage, err error) {
+	var (
+		found bool
+		name  string
+	)
+
+	for _, name = range names {
+		found = false
+		for _, keyUsageName := range keyUsageNames {
+			if keyUsageName.name == name {
+				keyUsage |= keyUsageName.keyUsage
+				found = true
+				break
+			}
+		}
+		if !found {
+			keyUsage = 0
+			err = fmt.Errorf("keyUsage \"%s\" not supported", name)
+			return
+		}
+	}
+
+	return
+}
+
+// parseExtKeyUsageNames returns the x509.ExtKeyUsages named by names (each as
+// reported in CertInfo.ExtKeyUsage). No names yields a nil (default) ExtKeyUsage.
+//
+func parseExtKeyUsageNames(names []string) (extKeyUsage []x509.ExtKeyUsage, err error) {
+	var (
+		candidate x509.ExtKeyUsage
+		found     bool
+		name      string
+	)
+
+	for _, name = range names {
+		found = false
+		for candidate = range extKeyUsageNames {
+			if extKeyUsageNames[candidate] == name {
+				extKeyUsage = append(extKeyUsage, candidate)
+				found = true
+				break
+			}
+		}
+		if !found {
+			extKeyUsage = nil
+			err = fmt.Errorf("extKeyUsage \"%s\" not supported", name)
+			return
+		}
+	}
+
+	return
+}
+
+func parseIPAddresses(ipAddressStrings []string) (ipAddresses []net.IP, err error) {
+	var (
+		ipAddress       net.IP
+		ipAddressString string
+	)
+
+	ipAddresses = make([]net.IP, 0, len(ipAddressStrings))
+
+	for _, ipAddressString = range ipAddressStrings {
+		ipAddress = net.ParseIP(ipAddressString)
+		if nil == ipAddress {
+			ipAddresses = nil
+			err = fmt.Errorf("ipAddresses contains invalid IP Address \"%s\"", ipAddressString)
+			return
+		}
+		ipAddresses = append(ipAddresses, ipAddress)
+	}
+
+	return
+}
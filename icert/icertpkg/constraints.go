@@ -0,0 +1,208 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// applyConstraints sets the MaxPathLen and name constraints of a CA Certificate
+// template as specified in constraints. These may only be specified for a CA.
+//
+func applyConstraints(x509CertificateTemplate *x509.Certificate, constraints *CAConstraints) (err error) {
+	var (
+		hasNameConstraints bool
+	)
+
+	hasNameConstraints = (0 != len(constraints.PermittedDNSDomains)) ||
+		(0 != len(constraints.ExcludedDNSDomains)) ||
+		(0 != len(constraints.PermittedIPRanges)) ||
+		(0 != len(constraints.ExcludedIPRanges)) ||
+		(0 != len(constraints.PermittedEmailAddresses)) ||
+		(0 != len(constraints.ExcludedEmailAddresses))
+
+	if !x509CertificateTemplate.IsCA {
+		if hasNameConstraints || ((0 != constraints.MaxPathLen) && (-1 != constraints.MaxPathLen)) || constraints.MaxPathLenZero {
+			err = fmt.Errorf("Constraints may only be specified for a CA Certificate")
+		} else {
+			err = nil
+		}
+		return
+	}
+
+	if constraints.MaxPathLen < -1 {
+		err = fmt.Errorf("MaxPathLen (%d) must not be less than -1", constraints.MaxPathLen)
+		return
+	}
+	if constraints.MaxPathLenZero && (0 != constraints.MaxPathLen) {
+		err = fmt.Errorf("MaxPathLenZero requires a MaxPathLen of zero (not %d)", constraints.MaxPathLen)
+		return
+	}
+
+	if constraints.MaxPathLenZero {
+		x509CertificateTemplate.MaxPathLen = 0
+		x509CertificateTemplate.MaxPathLenZero = true
+	} else if constraints.MaxPathLen <= 0 {
+		x509CertificateTemplate.MaxPathLen = -1
+	} else {
+		x509CertificateTemplate.MaxPathLen = constraints.MaxPathLen
+	}
+
+	if hasNameConstraints {
+		x509CertificateTemplate.PermittedDNSDomainsCritical = true
+		x509CertificateTemplate.PermittedDNSDomains = constraints.PermittedDNSDomains
+		x509CertificateTemplate.ExcludedDNSDomains = constraints.ExcludedDNSDomains
+		x509CertificateTemplate.PermittedIPRanges = constraints.PermittedIPRanges
+		x509CertificateTemplate.ExcludedIPRanges = constraints.ExcludedIPRanges
+		x509CertificateTemplate.PermittedEmailAddresses = constraints.PermittedEmailAddresses
+		x509CertificateTemplate.ExcludedEmailAddresses = constraints.ExcludedEmailAddresses
+	}
+
+	err = nil
+	return
+}
+
+// checkNameConstraints verifies that each SAN of the Endpoint Certificate about to
+// be generated from x509CertificateTemplate is permitted (and not excluded) by the
+// name constraints of the CA so that chain verification will not later reject it.
+//
+func (ca *CA) checkNameConstraints(x509CertificateTemplate *x509.Certificate) (err error) {
+	var (
+		caCert *x509.Certificate
+	)
+
+	caCert = ca.x509Certificate
+
+	for _, dnsName := range x509CertificateTemplate.DNSNames {
+		err = checkNameConstraint("DNS name", dnsName, caCert.PermittedDNSDomains, caCert.ExcludedDNSDomains, matchDomainConstraint)
+		if nil != err {
+			return
+		}
+	}
+
+	for _, ipAddress := range x509CertificateTemplate.IPAddresses {
+		err = checkIPConstraint(ipAddress, caCert.PermittedIPRanges, caCert.ExcludedIPRanges)
+		if nil != err {
+			return
+		}
+	}
+
+	for _, emailAddress := range x509CertificateTemplate.EmailAddresses {
+		err = checkNameConstraint("email address", emailAddress, caCert.PermittedEmailAddresses, caCert.ExcludedEmailAddresses, matchEmailConstraint)
+		if nil != err {
+			return
+		}
+	}
+
+	for _, uri := range x509CertificateTemplate.URIs {
+		err = checkNameConstraint("URI", uri.Hostname(), caCert.PermittedURIDomains, caCert.ExcludedURIDomains, matchDomainConstraint)
+		if nil != err {
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+func checkNameConstraint(kind string, name string, permitted []string, excluded []string, match func(name string, constraint string) bool) (err error) {
+	var (
+		constraint string
+		ok         bool
+	)
+
+	for _, constraint = range excluded {
+		if match(name, constraint) {
+			err = fmt.Errorf("%s \"%s\" is excluded by the CA's name constraint \"%s\"", kind, name, constraint)
+			return
+		}
+	}
+
+	if 0 != len(permitted) {
+		ok = false
+		for _, constraint = range permitted {
+			if match(name, constraint) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			err = fmt.Errorf("%s \"%s\" is not permitted by the CA's name constraints %v", kind, name, permitted)
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+func checkIPConstraint(ipAddress net.IP, permitted []*net.IPNet, excluded []*net.IPNet) (err error) {
+	var (
+		ipNet *net.IPNet
+		ok    bool
+	)
+
+	for _, ipNet = range excluded {
+		if ipNet.Contains(ipAddress) {
+			err = fmt.Errorf("IP address %v is excluded by the CA's name constraint %v", ipAddress, ipNet)
+			return
+		}
+	}
+
+	if 0 != len(permitted) {
+		ok = false
+		for _, ipNet = range permitted {
+			if ipNet.Contains(ipAddress) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			err = fmt.Errorf("IP address %v is not permitted by the CA's name constraints %v", ipAddress, permitted)
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+// matchDomainConstraint reports whether domain is within constraint. A constraint
+// of "example.com" matches "example.com" and any subdomain of it while one of
+// ".example.com" matches only subdomains.
+//
+func matchDomainConstraint(domain string, constraint string) bool {
+	domain = strings.ToLower(domain)
+	constraint = strings.ToLower(constraint)
+
+	if "" == constraint {
+		return true
+	}
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(domain, constraint)
+	}
+
+	return (domain == constraint) || strings.HasSuffix(domain, "."+constraint)
+}
+
+// matchEmailConstraint reports whether emailAddress is within constraint which
+// is either a specific mailbox (containing '@') or a domain constraint applied to
+// the domain of emailAddress.
+//
+func matchEmailConstraint(emailAddress string, constraint string) bool {
+	var (
+		atIndex int
+	)
+
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(emailAddress, constraint)
+	}
+
+	atIndex = strings.LastIndex(emailAddress, "@")
+
+	return matchDomainConstraint(emailAddress[atIndex+1:], constraint)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+func crossSignCert(existingCertPEMPath string, newCACertPEMPath string, newCAKeyPEMPath string, outCertPEMPath string) (err error) {
+	var (
+		crossSignedX509Certificate         []byte
+		crossSignedX509CertificateTemplate *x509.Certificate
+		existingX509Certificate            *x509.Certificate
+		newCA                              *CA
+		notAfter                           time.Time
+		serialNumber                       *big.Int
+	)
+
+	// Writing only a Certificate to outCertPEMPath must not clobber any input (or
+	// the private key a combined input may hold)
+
+	if (outCertPEMPath == existingCertPEMPath) || (outCertPEMPath == newCACertPEMPath) || (outCertPEMPath == newCAKeyPEMPath) {
+		err = fmt.Errorf("outCertPEMPath \"%s\" must differ from existingCertPEMPath, newCACertPEMPath, and newCAKeyPEMPath", outCertPEMPath)
+		return
+	}
+
+	existingX509Certificate, err = loadFirstCert(existingCertPEMPath)
+	if nil != err {
+		return
+	}
+
+	newCA, err = loadCA(newCACertPEMPath, newCAKeyPEMPath)
+	if nil != err {
+		return
+	}
+
+	if !newCA.x509Certificate.IsCA {
+		err = fmt.Errorf("%w: \"%s\" in \"%s\"", ErrNotCACert, newCA.x509Certificate.Subject, newCACertPEMPath)
+		return
+	}
+
+	notAfter, err = newCA.notAfter(existingX509Certificate.NotBefore, existingX509Certificate.NotAfter.Sub(existingX509Certificate.NotBefore), false)
+	if nil != err {
+		return
+	}
+
+	serialNumber, err = genSerialNumber(rand.Reader)
+	if nil != err {
+		return
+	}
+
+	crossSignedX509CertificateTemplate = &x509.Certificate{
+		SerialNumber:          serialNumber,
+		RawSubject:            existingX509Certificate.RawSubject,
+		NotBefore:             existingX509Certificate.NotBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              existingX509Certificate.KeyUsage,
+		ExtKeyUsage:           existingX509Certificate.ExtKeyUsage,
+		UnknownExtKeyUsage:    existingX509Certificate.UnknownExtKeyUsage,
+		BasicConstraintsValid: existingX509Certificate.BasicConstraintsValid,
+		IsCA:                  existingX509Certificate.IsCA,
+		MaxPathLen:            existingX509Certificate.MaxPathLen,
+		MaxPathLenZero:        existingX509Certificate.MaxPathLenZero,
+		SubjectKeyId:          existingX509Certificate.SubjectKeyId,
+		AuthorityKeyId:        newCA.x509Certificate.SubjectKeyId,
+		DNSNames:              existingX509Certificate.DNSNames,
+		EmailAddresses:        existingX509Certificate.EmailAddresses,
+		IPAddresses:           existingX509Certificate.IPAddresses,
+		URIs:                  existingX509Certificate.URIs,
+	}
+
+	err = newCA.checkNameConstraints(crossSignedX509CertificateTemplate)
+	if nil != err {
+		return
+	}
+
+	crossSignedX509Certificate, err = x509.CreateCertificate(rand.Reader, crossSignedX509CertificateTemplate, newCA.x509Certificate, existingX509Certificate.PublicKey, newCA.signer)
+	if nil != err {
+		return
+	}
+
+	err = writeCertAndKeyFiles(crossSignedX509Certificate, nil, outCertPEMPath, "", false)
+	if nil != err {
+		return
+	}
+
+	err = reportIssuance(IssuanceKindCrossSign, crossSignedX509Certificate, outCertPEMPath)
+
+	return
+}
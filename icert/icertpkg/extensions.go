@@ -0,0 +1,135 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+)
+
+// tlsFeatureStatusRequest is the TLS Feature (RFC 7633) value of the
+// status_request TLS extension (i.e. OCSP Must-Staple).
+//
+const tlsFeatureStatusRequest = 5
+
+var oidExtensionTLSFeature = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+// builtInExtensions lists the extensions that icertpkg (via crypto/x509) derives
+// from its own arguments and options and which CertOptions.ExtraExtensions may
+// therefore not supply.
+//
+var builtInExtensions = []struct {
+	oid  asn1.ObjectIdentifier
+	name string
+}{
+	{asn1.ObjectIdentifier{2, 5, 29, 14}, "Subject Key Identifier"},
+	{asn1.ObjectIdentifier{2, 5, 29, 15}, "Key Usage"},
+	{asn1.ObjectIdentifier{2, 5, 29, 17}, "Subject Alternative Name"},
+	{asn1.ObjectIdentifier{2, 5, 29, 19}, "Basic Constraints"},
+	{asn1.ObjectIdentifier{2, 5, 29, 30}, "Name Constraints"},
+	{asn1.ObjectIdentifier{2, 5, 29, 31}, "CRL Distribution Points"},
+	{asn1.ObjectIdentifier{2, 5, 29, 35}, "Authority Key Identifier"},
+	{asn1.ObjectIdentifier{2, 5, 29, 37}, "Extended Key Usage"},
+	{asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}, "Authority Information Access"},
+}
+
+// applyExtraExtensions appends the TLS Feature extension (if options.MustStaple
+// is set) followed by options.ExtraExtensions to the ExtraExtensions of
+// x509CertificateTemplate. Any OID appearing more than once (including that of
+// a built-in extension) is rejected.
+//
+func applyExtraExtensions(x509CertificateTemplate *x509.Certificate, options *CertOptions) (err error) {
+	var (
+		builtInExtension int
+		certExtension    CertExtension
+		extension        pkix.Extension
+		extensionIndex   int
+		extensionName    string
+		extensionNames   map[string]string
+		ok               bool
+		oid              string
+		tlsFeatureValue  []byte
+	)
+
+	extensionNames = make(map[string]string)
+
+	for builtInExtension = range builtInExtensions {
+		extensionNames[builtInExtensions[builtInExtension].oid.String()] = "built-in " + builtInExtensions[builtInExtension].name + " extension"
+	}
+
+	if options.MustStaple {
+		tlsFeatureValue, err = asn1.Marshal([]int{tlsFeatureStatusRequest})
+		if nil != err {
+			return
+		}
+
+		x509CertificateTemplate.ExtraExtensions = append(x509CertificateTemplate.ExtraExtensions, pkix.Extension{Id: oidExtensionTLSFeature, Value: tlsFeatureValue})
+
+		extensionNames[oidExtensionTLSFeature.String()] = "TLS Feature extension of MustStaple"
+	}
+
+	for extensionIndex, extension = range options.ExtraExtensions {
+		oid = extension.Id.String()
+
+		extensionName, ok = extensionNames[oid]
+		if ok {
+			err = fmt.Errorf("ExtraExtensions[%d] OID %s duplicates the %s", extensionIndex, oid, extensionName)
+			return
+		}
+
+		extensionNames[oid] = fmt.Sprintf("extension of ExtraExtensions[%d]", extensionIndex)
+
+		x509CertificateTemplate.ExtraExtensions = append(x509CertificateTemplate.ExtraExtensions, extension)
+	}
+
+	for extensionIndex, certExtension = range options.Extensions {
+		if 0 == len(certExtension.OID) {
+			err = fmt.Errorf("Extensions[%d] OID must not be empty", extensionIndex)
+			return
+		}
+
+		oid = certExtension.OID.String()
+
+		extensionName, ok = extensionNames[oid]
+		if ok {
+			err = fmt.Errorf("Extensions[%d] OID %s duplicates the %s", extensionIndex, oid, extensionName)
+			return
+		}
+
+		extensionNames[oid] = fmt.Sprintf("extension of Extensions[%d]", extensionIndex)
+
+		x509CertificateTemplate.ExtraExtensions = append(x509CertificateTemplate.ExtraExtensions, pkix.Extension{Id: certExtension.OID, Critical: certExtension.Critical, Value: certExtension.Value})
+	}
+
+	return
+}
+
+// readExtension returns the value and criticality of the extension identified
+// by oid in the (first) Certificate in certPEMPath.
+//
+func readExtension(certPEMPath string, oid asn1.ObjectIdentifier) (value []byte, critical bool, err error) {
+	var (
+		extension       pkix.Extension
+		x509Certificate *x509.Certificate
+	)
+
+	x509Certificate, err = loadFirstCert(certPEMPath)
+	if nil != err {
+		return
+	}
+
+	for _, extension = range x509Certificate.Extensions {
+		if extension.Id.Equal(oid) {
+			value = extension.Value
+			critical = extension.Critical
+			return
+		}
+	}
+
+	err = fmt.Errorf("%w: OID %s in \"%s\"", ErrExtensionNotFound, oid, certPEMPath)
+
+	return
+}
@@ -4,7 +4,9 @@
 package icertpkg
 
 import (
+	"context"
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
@@ -12,102 +14,1352 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/big"
 	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
-func genCACert(generateKeyAlgorithm string, subject pkix.Name, ttl time.Duration, certFile string, keyFile string) (err error) {
+func genCACert(ctx context.Context, generateKeyAlgorithm string, subject pkix.Name, ttl time.Duration, certFile string, keyFile string, options *CertOptions) (err error) {
 	var (
 		caX509Certificate         []byte
 		caX509CertificateTemplate *x509.Certificate
-		certPEM                   []byte
-		ed25519PrivateKey         ed25519.PrivateKey
-		ed25519PublicKey          ed25519.PublicKey
-		keyPEM                    []byte
+		notBefore                 time.Time
+		privateKey                crypto.Signer
 		pkcs8PrivateKey           []byte
-		rsaPrivateKey             *rsa.PrivateKey
-		rsaPublicKey              crypto.PublicKey
 		serialNumber              *big.Int
-		serialNumberMax           *big.Int
 		timeNow                   time.Time
+		unlock                    func()
+	)
+
+	if nil == options {
+		options = &CertOptions{}
+	}
+
+	serialNumber, err = genSerialNumber(options.randReader())
+	if nil != err {
+		return
+	}
+
+	timeNow = options.now()
+
+	err = checkContext(ctx)
+	if nil != err {
+		return
+	}
+
+	unlock, err = options.lockOutputs(ctx, certFile)
+	if nil != err {
+		return
+	}
+	defer unlock()
+
+	if options.skipIfValid(certFile, keyFile, nil) {
+		return
+	}
+
+	notBefore = options.notBefore(timeNow)
+
+	caX509CertificateTemplate = &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(ttl),
+		IsCA:                  true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	err = applyCommonName(caX509CertificateTemplate, options)
+	if nil != err {
+		return
+	}
+
+	err = applyUsage(caX509CertificateTemplate, &options.Usage)
+	if nil != err {
+		return
+	}
+
+	err = applyRevocationURLs(caX509CertificateTemplate, options, nil)
+	if nil != err {
+		return
+	}
+
+	err = applyExtraExtensions(caX509CertificateTemplate, options)
+	if nil != err {
+		return
+	}
+
+	err = applyConstraints(caX509CertificateTemplate, &options.Constraints)
+	if nil != err {
+		return
+	}
+
+	privateKey, err = options.privateKey(ctx, generateKeyAlgorithm, certFile, keyFile)
+	if nil != err {
+		return
+	}
+
+	err = applySignatureAlgorithm(caX509CertificateTemplate, privateKey, options.SignatureAlgorithm)
+	if nil != err {
+		return
+	}
+
+	caX509CertificateTemplate.SubjectKeyId, err = subjectKeyID(privateKey.Public())
+	if nil != err {
+		return
+	}
+
+	caX509Certificate, err = x509.CreateCertificate(options.randReader(), caX509CertificateTemplate, caX509CertificateTemplate, privateKey.Public(), privateKey)
+	if nil != err {
+		return
+	}
+
+	err = lintGeneratedCert(caX509Certificate, options)
+	if nil != err {
+		return
+	}
+
+	if "" == options.ExistingKeyFile {
+		pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(privateKey)
+		if nil != err {
+			return
+		}
+	}
+
+	// Abandon (leaving no output files behind) if cancelled while generating
+
+	err = checkContext(ctx)
+	if nil != err {
+		return
+	}
+
+	err = options.writeCertAndKey(caX509Certificate, pkcs8PrivateKey, certFile, keyFile, options.Overwrite)
+	if nil != err {
+		return
+	}
+
+	err = reportIssuance(options.issuanceKindOr(IssuanceKindCA), caX509Certificate, options.issuanceDestination(certFile))
+
+	return
+}
+
+func loadCA(caCertFile string, caKeyFile string) (ca *CA, err error) {
+	ca, err = readCA(caCertFile, caKeyFile)
+	if nil != err {
+		return
+	}
+
+	err = ca.checkNotExpired(time.Now())
+	if nil != err {
+		ca = nil
+		return
+	}
+
+	return
+}
+
+// readCA is loadCA() without checking whether the CA Certificate has expired.
+//
+func readCA(caCertFile string, caKeyFile string) (ca *CA, err error) {
+	var (
+		caTLSCertificate tls.Certificate
+	)
+
+	caTLSCertificate, err = loadKeyPair(caCertFile, caKeyFile)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("%w: %v", ErrCANotFound, err)
+		}
+		return
+	}
+
+	ca, err = newCA(caTLSCertificate, "CA private key in \""+caKeyFile+"\"")
+
+	return
+}
+
+// newCA returns the CA of caTLSCertificate (whose private key is described by
+// caKeyDescription should it be unable to sign).
+//
+func newCA(caTLSCertificate tls.Certificate, caKeyDescription string) (ca *CA, err error) {
+	var (
+		ok bool
+	)
+
+	ca = &CA{}
+
+	ca.x509Certificate, err = x509.ParseCertificate(caTLSCertificate.Certificate[0])
+	if nil != err {
+		ca = nil
+		return
+	}
+
+	ca.signer, ok = caTLSCertificate.PrivateKey.(crypto.Signer)
+	if !ok {
+		ca = nil
+		err = fmt.Errorf("%s cannot be used for signing", caKeyDescription)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func loadCAWithSigner(caCertFile string, signer crypto.Signer) (ca *CA, err error) {
+	var (
+		caX509Certificate *x509.Certificate
+		publicKey         interface{ Equal(crypto.PublicKey) bool }
+		ok                bool
+	)
+
+	if nil == signer {
+		err = fmt.Errorf("signer must not be nil")
+		return
+	}
+
+	caX509Certificate, err = loadFirstCert(caCertFile)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("%w: %v", ErrCANotFound, err)
+		}
+		return
+	}
+
+	if !caX509Certificate.IsCA {
+		err = fmt.Errorf("%w: \"%s\" in \"%s\"", ErrNotCACert, caX509Certificate.Subject, caCertFile)
+		return
+	}
+
+	publicKey, ok = signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(caX509Certificate.PublicKey) {
+		err = fmt.Errorf("%w: CA Certificate in \"%s\" was not issued for the public key of signer", ErrKeyCertMismatch, caCertFile)
+		return
+	}
+
+	ca = &CA{
+		x509Certificate: caX509Certificate,
+		signer:          signer,
+	}
+
+	err = ca.checkNotExpired(time.Now())
+	if nil != err {
+		ca = nil
+		return
+	}
+
+	return
+}
+
+func (ca *CA) checkNotExpired(timeNow time.Time) (err error) {
+	if timeNow.After(ca.x509Certificate.NotAfter) {
+		err = fmt.Errorf("%w: NotAfter (%v) has passed", ErrCAExpired, ca.x509Certificate.NotAfter)
+	} else {
+		err = nil
+	}
+
+	return
+}
+
+func genEndpointCert(ctx context.Context, generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, caCertFile string, caKeyFile string, endpointCertFile string, endpointKeyFile string, options *CertOptions) (err error) {
+	var (
+		ca *CA
+	)
+
+	// Expiry of the CA is checked by ca.genEndpointCert() against options.Now
+
+	ca, err = readCA(caCertFile, caKeyFile)
+	if nil != err {
+		return
+	}
+
+	err = ca.genEndpointCert(ctx, generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, endpointCertFile, endpointKeyFile, options)
+
+	return
+}
+
+func (ca *CA) genEndpointCert(ctx context.Context, generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, endpointCertFile string, endpointKeyFile string, options *CertOptions) (err error) {
+	var (
+		notAfter                time.Time
+		notBefore               time.Time
+		parsedURIs              []*url.URL
+		pkcs8PrivateKey         []byte
+		privateKey              crypto.Signer
+		serialNumber            *big.Int
+		timeNow                 time.Time
+		unlock                  func()
+		x509Certificate         []byte
+		x509CertificateTemplate *x509.Certificate
+	)
+
+	if nil == options {
+		options = &CertOptions{}
+	}
+
+	if nil != options.serialNumber {
+		serialNumber = options.serialNumber
+	} else {
+		serialNumber, err = genSerialNumber(options.randReader())
+		if nil != err {
+			return
+		}
+	}
+
+	timeNow = options.now()
+
+	err = ca.checkNotExpired(timeNow)
+	if nil != err {
+		return
+	}
+
+	err = checkContext(ctx)
+	if nil != err {
+		return
+	}
+
+	unlock, err = options.lockOutputs(ctx, endpointCertFile)
+	if nil != err {
+		return
+	}
+	defer unlock()
+
+	if options.skipIfValid(endpointCertFile, endpointKeyFile, ca.x509Certificate) {
+		return
+	}
+
+	notBefore = options.notBefore(timeNow)
+
+	err = validateEmailAddresses(emailAddresses)
+	if nil != err {
+		return
+	}
+
+	parsedURIs, err = parseURIs(uris)
+	if nil != err {
+		return
+	}
+
+	notAfter, err = ca.notAfter(notBefore, ttl, options.ClampToCAExpiry)
+	if nil != err {
+		return
+	}
+
+	x509CertificateTemplate = &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               subject,
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
+		EmailAddresses:        emailAddresses,
+		URIs:                  parsedURIs,
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	err = applyCommonName(x509CertificateTemplate, options)
+	if nil != err {
+		return
+	}
+
+	err = applyUsage(x509CertificateTemplate, &options.Usage)
+	if nil != err {
+		return
+	}
+
+	err = applyRevocationURLs(x509CertificateTemplate, options, &ca.issuedCertURLs)
+	if nil != err {
+		return
+	}
+
+	err = applyExtraExtensions(x509CertificateTemplate, options)
+	if nil != err {
+		return
+	}
+
+	err = applyConstraints(x509CertificateTemplate, &options.Constraints)
+	if nil != err {
+		return
+	}
+
+	err = ca.checkNameConstraints(x509CertificateTemplate)
+	if nil != err {
+		return
+	}
+
+	privateKey, err = options.privateKey(ctx, generateKeyAlgorithm, endpointCertFile, endpointKeyFile)
+	if nil != err {
+		return
+	}
+
+	err = applySignatureAlgorithm(x509CertificateTemplate, ca.signer, options.SignatureAlgorithm)
+	if nil != err {
+		return
+	}
+
+	x509CertificateTemplate.SubjectKeyId, err = subjectKeyID(privateKey.Public())
+	if nil != err {
+		return
+	}
+
+	x509CertificateTemplate.AuthorityKeyId = ca.x509Certificate.SubjectKeyId
+
+	x509Certificate, err = x509.CreateCertificate(options.randReader(), x509CertificateTemplate, ca.x509Certificate, privateKey.Public(), ca.signer)
+	if nil != err {
+		return
+	}
+
+	err = checkGeneratedSANs(x509Certificate, dnsNames, ipAddresses)
+	if nil != err {
+		return
+	}
+
+	err = lintGeneratedCert(x509Certificate, options)
+	if nil != err {
+		return
+	}
+
+	if "" == options.ExistingKeyFile {
+		pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(privateKey)
+		if nil != err {
+			return
+		}
+	}
+
+	// Abandon (leaving no output files behind) if cancelled while generating
+
+	err = checkContext(ctx)
+	if nil != err {
+		return
+	}
+
+	err = options.writeCertAndKey(x509Certificate, pkcs8PrivateKey, endpointCertFile, endpointKeyFile, true)
+	if nil != err {
+		return
+	}
+
+	err = reportIssuance(options.issuanceKindOr(IssuanceKindEndpoint), x509Certificate, options.issuanceDestination(endpointCertFile))
+
+	return
+}
+
+func genSelfSignedCert(ctx context.Context, generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, ttl time.Duration, certFile string, keyFile string, options *CertOptions) (err error) {
+	var (
+		notBefore               time.Time
+		pkcs8PrivateKey         []byte
+		privateKey              crypto.Signer
+		serialNumber            *big.Int
+		timeNow                 time.Time
+		unlock                  func()
+		x509Certificate         []byte
+		x509CertificateTemplate *x509.Certificate
+	)
+
+	if nil == options {
+		options = &CertOptions{}
+	}
+
+	serialNumber, err = genSerialNumber(options.randReader())
+	if nil != err {
+		return
+	}
+
+	timeNow = options.now()
+
+	err = checkContext(ctx)
+	if nil != err {
+		return
+	}
+
+	unlock, err = options.lockOutputs(ctx, certFile)
+	if nil != err {
+		return
+	}
+	defer unlock()
+
+	if options.skipIfValid(certFile, keyFile, nil) {
+		return
+	}
+
+	notBefore = options.notBefore(timeNow)
+
+	x509CertificateTemplate = &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               subject,
+		DNSNames:              dnsNames,
+		IPAddresses:           ipAddresses,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(ttl),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	err = applyCommonName(x509CertificateTemplate, options)
+	if nil != err {
+		return
+	}
+
+	err = applyUsage(x509CertificateTemplate, &options.Usage)
+	if nil != err {
+		return
+	}
+
+	err = applyRevocationURLs(x509CertificateTemplate, options, nil)
+	if nil != err {
+		return
+	}
+
+	err = applyExtraExtensions(x509CertificateTemplate, options)
+	if nil != err {
+		return
+	}
+
+	err = applyConstraints(x509CertificateTemplate, &options.Constraints)
+	if nil != err {
+		return
+	}
+
+	privateKey, err = options.privateKey(ctx, generateKeyAlgorithm, certFile, keyFile)
+	if nil != err {
+		return
+	}
+
+	err = applySignatureAlgorithm(x509CertificateTemplate, privateKey, options.SignatureAlgorithm)
+	if nil != err {
+		return
+	}
+
+	x509CertificateTemplate.SubjectKeyId, err = subjectKeyID(privateKey.Public())
+	if nil != err {
+		return
+	}
+
+	x509Certificate, err = x509.CreateCertificate(options.randReader(), x509CertificateTemplate, x509CertificateTemplate, privateKey.Public(), privateKey)
+	if nil != err {
+		return
+	}
+
+	err = checkGeneratedSANs(x509Certificate, dnsNames, ipAddresses)
+	if nil != err {
+		return
+	}
+
+	err = lintGeneratedCert(x509Certificate, options)
+	if nil != err {
+		return
+	}
+
+	if "" == options.ExistingKeyFile {
+		pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(privateKey)
+		if nil != err {
+			return
+		}
+	}
+
+	// Abandon (leaving no output files behind) if cancelled while generating
+
+	err = checkContext(ctx)
+	if nil != err {
+		return
+	}
+
+	err = writeCertAndKeyFiles(x509Certificate, pkcs8PrivateKey, certFile, keyFile, true)
+	if nil != err {
+		return
+	}
+
+	err = reportIssuance(IssuanceKindSelfSigned, x509Certificate, certFile)
+
+	return
+}
+
+// checkGeneratedSANs parses x509Certificate back verifying that it holds each of
+// the requested dnsNames and ipAddresses as a SAN (e.g. catching a nil net.IP
+// from a net.ParseIP() of a malformed string).
+//
+func checkGeneratedSANs(x509Certificate []byte, dnsNames []string, ipAddresses []net.IP) (err error) {
+	var (
+		dnsName               string
+		found                 bool
+		ipAddress             net.IP
+		ipAddressIndex        int
+		parseErr              error
+		parsedDNSName         string
+		parsedIP              net.IP
+		parsedX509Certificate *x509.Certificate
+	)
+
+	parsedX509Certificate, parseErr = x509.ParseCertificate(x509Certificate)
+
+	for ipAddressIndex, ipAddress = range ipAddresses {
+		if (net.IPv4len != len(ipAddress)) && (net.IPv6len != len(ipAddress)) {
+			err = fmt.Errorf("%w: ipAddresses[%d] is a malformed IP Address of length %d (e.g. the nil returned by net.ParseIP() of an invalid string)", ErrSANMismatch, ipAddressIndex, len(ipAddress))
+			return
+		}
+	}
+
+	if nil != parseErr {
+		err = fmt.Errorf("%w: generated Certificate cannot be parsed back: %v", ErrSANMismatch, parseErr)
+		return
+	}
+
+	for _, dnsName = range dnsNames {
+		found = false
+		for _, parsedDNSName = range parsedX509Certificate.DNSNames {
+			if parsedDNSName == dnsName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			err = fmt.Errorf("%w: requested DNS SAN \"%s\" missing from generated Certificate (having %v)", ErrSANMismatch, dnsName, parsedX509Certificate.DNSNames)
+			return
+		}
+	}
+
+	for _, ipAddress = range ipAddresses {
+		found = false
+		for _, parsedIP = range parsedX509Certificate.IPAddresses {
+			if parsedIP.Equal(ipAddress) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			err = fmt.Errorf("%w: requested IP SAN %v missing from generated Certificate (having %v)", ErrSANMismatch, ipAddress, parsedX509Certificate.IPAddresses)
+			return
+		}
+	}
+
+	return
+}
+
+func validateEmailAddresses(emailAddresses []string) (err error) {
+	var (
+		emailAddressSplit []string
+	)
+
+	for _, emailAddress := range emailAddresses {
+		emailAddressSplit = strings.Split(emailAddress, "@")
+		if (2 != len(emailAddressSplit)) || ("" == emailAddressSplit[0]) || ("" == emailAddressSplit[1]) {
+			err = fmt.Errorf("email address \"%s\" must be of the form local@domain", emailAddress)
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+func parseURIs(uris []string) (parsedURIs []*url.URL, err error) {
+	var (
+		parsedURI *url.URL
+	)
+
+	parsedURIs = make([]*url.URL, 0, len(uris))
+
+	for _, uri := range uris {
+		parsedURI, err = url.Parse(uri)
+		if nil != err {
+			parsedURIs = nil
+			return
+		}
+		if !parsedURI.IsAbs() {
+			parsedURIs = nil
+			err = fmt.Errorf("URI \"%s\" must be absolute", uri)
+			return
+		}
+
+		parsedURIs = append(parsedURIs, parsedURI)
+	}
+
+	err = nil
+	return
+}
+
+// now returns options.Now() or, if not specified, time.Now().
+//
+func (options *CertOptions) now() time.Time {
+	if nil == options.Now {
+		return time.Now()
+	}
+
+	return options.Now()
+}
+
+// randReader returns options.Rand or, if not specified, crypto/rand.Reader.
+//
+func (options *CertOptions) randReader() io.Reader {
+	if nil == options.Rand {
+		return rand.Reader
+	}
+
+	return options.Rand
+}
+
+// notBefore returns options.NotBefore or, if not specified, timeNow.
+//
+func (options *CertOptions) notBefore(timeNow time.Time) time.Time {
+	if options.NotBefore.IsZero() {
+		return timeNow
+	}
+
+	return options.NotBefore
+}
+
+// applyUsage overrides the KeyUsage and/or ExtKeyUsage defaults already set in
+// x509CertificateTemplate with those specified in usage, rejecting combinations
+// that make no sense for the kind of Certificate being generated.
+//
+func applyUsage(x509CertificateTemplate *x509.Certificate, usage *CertUsageOptions) (err error) {
+	if 0 != usage.KeyUsage {
+		x509CertificateTemplate.KeyUsage = usage.KeyUsage
+	}
+
+	if nil != usage.ExtKeyUsage {
+		if (0 == len(usage.ExtKeyUsage)) && (0 == len(usage.UnknownExtKeyUsage)) {
+			err = fmt.Errorf("ExtKeyUsage, if specified, must not be empty unless UnknownExtKeyUsage is not")
+			return
+		}
+
+		x509CertificateTemplate.ExtKeyUsage = usage.ExtKeyUsage
+	}
+
+	if 0 != len(usage.UnknownExtKeyUsage) {
+		x509CertificateTemplate.UnknownExtKeyUsage = usage.UnknownExtKeyUsage
+	}
+
+	if x509CertificateTemplate.IsCA {
+		if 0 == (x509CertificateTemplate.KeyUsage & x509.KeyUsageCertSign) {
+			err = fmt.Errorf("KeyUsage of a CA Certificate must include KeyUsageCertSign")
+			return
+		}
+	} else {
+		if 0 != (x509CertificateTemplate.KeyUsage & (x509.KeyUsageCertSign | x509.KeyUsageCRLSign)) {
+			err = fmt.Errorf("KeyUsage of an Endpoint Certificate must not include KeyUsageCertSign or KeyUsageCRLSign")
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+// applyCommonName sets the Subject.CommonName of x509CertificateTemplate to
+// options.CommonName (if specified). Unless x509CertificateTemplate is a CA,
+// options.CommonNameSANMode then either requires that CommonName already be a
+// DNS (or, for an IP Address, IP) SAN or appends it as one.
+//
+func applyCommonName(x509CertificateTemplate *x509.Certificate, options *CertOptions) (err error) {
+	var (
+		dnsName   string
+		ipAddress net.IP
+		sanFound  bool
+	)
+
+	if "" == options.CommonName {
+		return
+	}
+
+	if ("" != x509CertificateTemplate.Subject.CommonName) && (options.CommonName != x509CertificateTemplate.Subject.CommonName) {
+		err = fmt.Errorf("CommonName \"%s\" conflicts with subject CommonName \"%s\"", options.CommonName, x509CertificateTemplate.Subject.CommonName)
+		return
+	}
+
+	switch options.CommonNameSANMode {
+	case "", CommonNameSANRequire, CommonNameSANAppend:
+	default:
+		err = fmt.Errorf("CommonNameSANMode \"%s\" not supported... must be one of \"%s\" or \"%s\"", options.CommonNameSANMode, CommonNameSANRequire, CommonNameSANAppend)
+		return
+	}
+
+	x509CertificateTemplate.Subject.CommonName = options.CommonName
+
+	if x509CertificateTemplate.IsCA {
+		return
+	}
+
+	ipAddress = net.ParseIP(options.CommonName)
+
+	if nil == ipAddress {
+		for _, dnsName = range x509CertificateTemplate.DNSNames {
+			if strings.EqualFold(dnsName, options.CommonName) {
+				sanFound = true
+				break
+			}
+		}
+	} else {
+		for _, sanIPAddress := range x509CertificateTemplate.IPAddresses {
+			if sanIPAddress.Equal(ipAddress) {
+				sanFound = true
+				break
+			}
+		}
+	}
+
+	if sanFound {
+		return
+	}
+
+	if CommonNameSANAppend != options.CommonNameSANMode {
+		err = fmt.Errorf("CommonName \"%s\" is not also a SAN (see CommonNameSANAppend)", options.CommonName)
+		return
+	}
+
+	// Append to copies so as not to modify the caller's slices
+
+	if nil == ipAddress {
+		x509CertificateTemplate.DNSNames = append(append(make([]string, 0, len(x509CertificateTemplate.DNSNames)+1), x509CertificateTemplate.DNSNames...), options.CommonName)
+	} else {
+		x509CertificateTemplate.IPAddresses = append(append(make([]net.IP, 0, len(x509CertificateTemplate.IPAddresses)+1), x509CertificateTemplate.IPAddresses...), ipAddress)
+	}
+
+	return
+}
+
+// applyRevocationURLs sets the Authority Information Access and CRL Distribution
+// Point URLs specified in options (each of which must be an absolute URL) in
+// x509CertificateTemplate. For each kind of URL options leaves unspecified,
+// those of issuedCertURLs (if non-nil) are used instead. Extensions for URLs
+// specified by neither are left absent.
+//
+func applyRevocationURLs(x509CertificateTemplate *x509.Certificate, options *CertOptions, issuedCertURLs *IssuedCertURLs) (err error) {
+	var (
+		issuingCertificateURLs []string
+		ocspServerURLs         []string
+	)
+
+	if "" != options.OCSPResponderURL {
+		_, err = parseURIs([]string{options.OCSPResponderURL})
+		if nil != err {
+			err = fmt.Errorf("invalid OCSPResponderURL: %v", err)
+			return
+		}
+
+		ocspServerURLs = append(ocspServerURLs, options.OCSPResponderURL)
+	}
+
+	if !options.renewedRevocationURLs {
+		err = checkHTTPURLs("OCSPServerURLs", options.OCSPServerURLs)
+		if nil != err {
+			return
+		}
+	}
+
+	ocspServerURLs = append(ocspServerURLs, options.OCSPServerURLs...)
+
+	if "" != options.IssuingCertificateURL {
+		_, err = parseURIs([]string{options.IssuingCertificateURL})
+		if nil != err {
+			err = fmt.Errorf("invalid IssuingCertificateURL: %v", err)
+			return
+		}
+
+		issuingCertificateURLs = append(issuingCertificateURLs, options.IssuingCertificateURL)
+	}
+
+	if !options.renewedRevocationURLs {
+		err = checkHTTPURLs("IssuingCertificateURLs", options.IssuingCertificateURLs)
+		if nil != err {
+			return
+		}
+	}
+
+	issuingCertificateURLs = append(issuingCertificateURLs, options.IssuingCertificateURLs...)
+
+	if 0 != len(options.CRLDistributionPoints) {
+		_, err = parseURIs(options.CRLDistributionPoints)
+		if nil != err {
+			err = fmt.Errorf("invalid CRLDistributionPoints: %v", err)
+			return
+		}
+
+		x509CertificateTemplate.CRLDistributionPoints = options.CRLDistributionPoints
+	}
+
+	// Fall back to the (already validated) defaults of the issuing CA
+
+	if nil != issuedCertURLs {
+		if 0 == len(ocspServerURLs) {
+			ocspServerURLs = issuedCertURLs.OCSPServerURLs
+		}
+		if 0 == len(issuingCertificateURLs) {
+			issuingCertificateURLs = issuedCertURLs.IssuingCertificateURLs
+		}
+		if 0 == len(x509CertificateTemplate.CRLDistributionPoints) {
+			x509CertificateTemplate.CRLDistributionPoints = issuedCertURLs.CRLDistributionPoints
+		}
+	}
+
+	if 0 != len(ocspServerURLs) {
+		x509CertificateTemplate.OCSPServer = ocspServerURLs
+	}
+	if 0 != len(issuingCertificateURLs) {
+		x509CertificateTemplate.IssuingCertificateURL = issuingCertificateURLs
+	}
+
+	err = nil
+	return
+}
+
+// checkHTTPURLs returns an error naming field unless each of urls is an
+// absolute http or https URL.
+//
+func checkHTTPURLs(field string, urls []string) (err error) {
+	var (
+		parsedURL *url.URL
+		rawURL    string
+	)
+
+	for _, rawURL = range urls {
+		parsedURL, err = url.Parse(rawURL)
+		if nil != err {
+			err = fmt.Errorf("invalid %s: %v", field, err)
+			return
+		}
+		if (("http" != parsedURL.Scheme) && ("https" != parsedURL.Scheme)) || ("" == parsedURL.Host) {
+			err = fmt.Errorf("invalid %s: \"%s\" must be an absolute http or https URL", field, rawURL)
+			return
+		}
+	}
+
+	return
+}
+
+func (ca *CA) withIssuedCertURLs(issuedCertURLs IssuedCertURLs) (caWithIssuedCertURLs *CA, err error) {
+	err = checkHTTPURLs("CRLDistributionPoints", issuedCertURLs.CRLDistributionPoints)
+	if nil != err {
+		return
+	}
+	err = checkHTTPURLs("OCSPServerURLs", issuedCertURLs.OCSPServerURLs)
+	if nil != err {
+		return
+	}
+	err = checkHTTPURLs("IssuingCertificateURLs", issuedCertURLs.IssuingCertificateURLs)
+	if nil != err {
+		return
+	}
+
+	// Copy the slices so later changes by the caller are not picked up
+
+	caWithIssuedCertURLs = &CA{
+		x509Certificate: ca.x509Certificate,
+		signer:          ca.signer,
+		issuedCertURLs: IssuedCertURLs{
+			CRLDistributionPoints:  append([]string(nil), issuedCertURLs.CRLDistributionPoints...),
+			OCSPServerURLs:         append([]string(nil), issuedCertURLs.OCSPServerURLs...),
+			IssuingCertificateURLs: append([]string(nil), issuedCertURLs.IssuingCertificateURLs...),
+		},
+	}
+
+	return
+}
+
+func genSerialNumber(randReader io.Reader) (serialNumber *big.Int, err error) {
+	var (
+		serialNumberMax *big.Int
 	)
 
 	serialNumberMax = big.NewInt(0)
 	_ = serialNumberMax.Exp(big.NewInt(2), big.NewInt(CertificateSerialNumberRandomBits), nil)
 
-	serialNumber, err = rand.Int(rand.Reader, serialNumberMax)
+	serialNumber, err = rand.Int(randReader, serialNumberMax)
+
+	return
+}
+
+// notAfter returns the NotAfter of a Certificate to be issued by ca whose validity
+// starts at notBefore and lasts for ttl. Should that extend past the NotAfter of
+// the CA Certificate by more than CAExpiryClampTolerance, an error wrapping
+// ErrExceedsCAExpiry is returned unless clampToCAExpiry is set (and notBefore
+// precedes the CA's NotAfter). Otherwise, the CA's NotAfter is returned instead.
+//
+func (ca *CA) notAfter(notBefore time.Time, ttl time.Duration, clampToCAExpiry bool) (notAfter time.Time, err error) {
+	notAfter = notBefore.Add(ttl)
+
+	if !notAfter.After(ca.x509Certificate.NotAfter) {
+		return
+	}
+
+	if (clampToCAExpiry || !notAfter.After(ca.x509Certificate.NotAfter.Add(CAExpiryClampTolerance))) && notBefore.Before(ca.x509Certificate.NotAfter) {
+		notAfter = ca.x509Certificate.NotAfter
+		return
+	}
+
+	err = fmt.Errorf("%w: NotAfter (%v) of a Certificate valid from %v for %v follows NotAfter (%v) of CA \"%s\"", ErrExceedsCAExpiry, notAfter, notBefore, ttl, ca.x509Certificate.NotAfter, ca.x509Certificate.Subject)
+	notAfter = time.Time{}
+
+	return
+}
+
+// privateKey returns the private key for a Certificate about to be generated for
+// certFile and keyFile. Unless options.ExistingKey or options.ExistingKeyFile is
+// specified, this will be a newly generated key.
+//
+func (options *CertOptions) privateKey(ctx context.Context, generateKeyAlgorithm string, certFile string, keyFile string) (privateKey crypto.Signer, err error) {
+	var (
+		ok bool
+	)
+
+	if nil != options.ExistingKey {
+		if "" != options.ExistingKeyFile {
+			err = fmt.Errorf("ExistingKey and ExistingKeyFile may not both be specified")
+			return
+		}
+		privateKey, ok = options.ExistingKey.(crypto.Signer)
+		if !ok {
+			err = fmt.Errorf("ExistingKey is a %T which cannot be used for signing", options.ExistingKey)
+			return
+		}
+		err = checkKeyAlgorithm(privateKey, generateKeyAlgorithm, "ExistingKey")
+		if nil != err {
+			privateKey = nil
+		}
+		return
+	}
+
+	if "" == options.ExistingKeyFile {
+		privateKey, err = genPrivateKeyContext(ctx, generateKeyAlgorithm, options.randReader())
+		return
+	}
+
+	if certFile == options.ExistingKeyFile {
+		err = fmt.Errorf("certFile must not be ExistingKeyFile \"%s\"", options.ExistingKeyFile)
+		return
+	}
+	if ("" != keyFile) && (keyFile != options.ExistingKeyFile) {
+		err = fmt.Errorf("keyFile \"%s\" must be either empty or ExistingKeyFile \"%s\"", keyFile, options.ExistingKeyFile)
+		return
+	}
+
+	privateKey, err = loadPrivateKey(options.ExistingKeyFile)
 	if nil != err {
 		return
 	}
 
-	timeNow = time.Now()
+	err = checkKeyAlgorithm(privateKey, generateKeyAlgorithm, "private key in \""+options.ExistingKeyFile+"\"")
+	if nil != err {
+		privateKey = nil
+		return
+	}
 
-	caX509CertificateTemplate = &x509.Certificate{
-		SerialNumber:          serialNumber,
-		Subject:               subject,
-		NotBefore:             timeNow,
-		NotAfter:              timeNow.Add(ttl),
-		IsCA:                  true,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
-		BasicConstraintsValid: true,
+	return
+}
+
+// signatureAlgorithmKeyTypes maps each supported CertOptions.SignatureAlgorithm
+// to the type of public key of a signer able to produce it.
+//
+var signatureAlgorithmKeyTypes = map[x509.SignatureAlgorithm]string{
+	x509.SHA256WithRSA:    "RSA",
+	x509.SHA384WithRSA:    "RSA",
+	x509.SHA512WithRSA:    "RSA",
+	x509.SHA256WithRSAPSS: "RSA",
+	x509.SHA384WithRSAPSS: "RSA",
+	x509.SHA512WithRSAPSS: "RSA",
+	x509.ECDSAWithSHA256:  "ECDSA",
+	x509.ECDSAWithSHA384:  "ECDSA",
+	x509.ECDSAWithSHA512:  "ECDSA",
+	x509.PureEd25519:      "Ed25519",
+}
+
+// applySignatureAlgorithm sets the SignatureAlgorithm of x509CertificateTemplate
+// to signatureAlgorithm (unless x509.UnknownSignatureAlgorithm selecting the
+// default for signer) after verifying that signer is able to produce it.
+//
+func applySignatureAlgorithm(x509CertificateTemplate *x509.Certificate, signer crypto.Signer, signatureAlgorithm x509.SignatureAlgorithm) (err error) {
+	var (
+		keyType       string
+		ok            bool
+		signerKeyType string
+	)
+
+	if x509.UnknownSignatureAlgorithm == signatureAlgorithm {
+		return
+	}
+
+	keyType, ok = signatureAlgorithmKeyTypes[signatureAlgorithm]
+	if !ok {
+		err = fmt.Errorf("SignatureAlgorithm %v not supported", signatureAlgorithm)
+		return
+	}
+
+	switch signer.Public().(type) {
+	case *rsa.PublicKey:
+		signerKeyType = "RSA"
+	case *ecdsa.PublicKey:
+		signerKeyType = "ECDSA"
+	case ed25519.PublicKey:
+		signerKeyType = "Ed25519"
+	default:
+		signerKeyType = fmt.Sprintf("%T", signer.Public())
 	}
 
+	if keyType != signerKeyType {
+		err = fmt.Errorf("SignatureAlgorithm %v requires an %s signing key but the signing key is %s", signatureAlgorithm, keyType, signerKeyType)
+		return
+	}
+
+	x509CertificateTemplate.SignatureAlgorithm = signatureAlgorithm
+
+	return
+}
+
+// checkKeyAlgorithm verifies that an existing privateKey (described in any error
+// by keyDescription) is of the type that generateKeyAlgorithm would have generated.
+// A generateKeyAlgorithm of "" accepts any privateKey. Only privateKey.Public() is
+// examined so privateKey need not be a concrete (i.e. exportable) private key.
+//
+func checkKeyAlgorithm(privateKey crypto.Signer, generateKeyAlgorithm string, keyDescription string) (err error) {
+	var (
+		keyAlgorithmMatches bool
+	)
+
 	switch generateKeyAlgorithm {
+	case "":
+		keyAlgorithmMatches = true
 	case GenerateKeyAlgorithmEd25519:
-		ed25519PublicKey, ed25519PrivateKey, err = ed25519.GenerateKey(rand.Reader)
-		if nil != err {
+		_, keyAlgorithmMatches = privateKey.Public().(ed25519.PublicKey)
+	case GenerateKeyAlgorithmRSA:
+		_, keyAlgorithmMatches = privateKey.Public().(*rsa.PublicKey)
+	default:
+		err = fmt.Errorf("%w: \"%s\"... must be one of \"%s\" or \"%s\"", ErrUnsupportedKeyAlgorithm, generateKeyAlgorithm, GenerateKeyAlgorithmEd25519, GenerateKeyAlgorithmRSA)
+		return
+	}
+
+	if !keyAlgorithmMatches {
+		err = fmt.Errorf("%s is a %T but generateKeyAlgorithm \"%s\" was requested", keyDescription, privateKey, generateKeyAlgorithm)
+		return
+	}
+
+	err = nil
+	return
+}
+
+// loadPrivateKey returns the first private key found in the PEM-encoded keyFile.
+// PKCS#8 ("PRIVATE KEY"), PKCS#1 ("RSA PRIVATE KEY"), and SEC 1 ("EC PRIVATE KEY")
+// encodings are supported. Other PEM blocks (e.g. a "CERTIFICATE") are skipped.
+//
+func loadPrivateKey(keyFile string) (privateKey crypto.Signer, err error) {
+	var (
+		keyPEM []byte
+	)
+
+	keyPEM, err = ioutil.ReadFile(keyFile)
+	if nil != err {
+		return
+	}
+
+	privateKey, err = parsePrivateKey(keyPEM, keyFile)
+
+	return
+}
+
+// parsePrivateKey is loadPrivateKey() for the PEM content keyPEM read from
+// keyFile.
+//
+func parsePrivateKey(keyPEM []byte, keyFile string) (privateKey crypto.Signer, err error) {
+	var (
+		blockIndex       int
+		ok               bool
+		parsedPrivateKey interface{}
+		pemBlock         *pem.Block
+	)
+
+	for blockIndex = 0; ; blockIndex++ {
+		pemBlock, keyPEM = pem.Decode(keyPEM)
+		if nil == pemBlock {
+			err = &ErrBadPEM{Path: keyFile, BlockIndex: -1, Err: errors.New("no private key found")}
 			return
 		}
 
-		caX509Certificate, err = x509.CreateCertificate(rand.Reader, caX509CertificateTemplate, caX509CertificateTemplate, ed25519PublicKey, ed25519PrivateKey)
+		switch pemBlock.Type {
+		case "PRIVATE KEY":
+			parsedPrivateKey, err = x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
+		case "RSA PRIVATE KEY":
+			parsedPrivateKey, err = x509.ParsePKCS1PrivateKey(pemBlock.Bytes)
+		case "EC PRIVATE KEY":
+			parsedPrivateKey, err = x509.ParseECPrivateKey(pemBlock.Bytes)
+		default:
+			continue
+		}
 		if nil != err {
+			err = &ErrBadPEM{Path: keyFile, BlockIndex: blockIndex, Err: fmt.Errorf("unable to parse %s: %v", pemBlock.Type, err)}
 			return
 		}
 
-		pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(ed25519PrivateKey)
-		if nil != err {
+		privateKey, ok = parsedPrivateKey.(crypto.Signer)
+		if !ok {
+			err = fmt.Errorf("private key in \"%s\" cannot be used for signing", keyFile)
 			return
 		}
+
+		err = nil
+		return
+	}
+}
+
+func genPrivateKey(generateKeyAlgorithm string, randReader io.Reader) (privateKey crypto.Signer, err error) {
+	switch generateKeyAlgorithm {
+	case GenerateKeyAlgorithmEd25519:
+		_, privateKey, err = ed25519.GenerateKey(randReader)
 	case GenerateKeyAlgorithmRSA:
-		rsaPrivateKey, err = rsa.GenerateKey(rand.Reader, GenerateKeyAlgorithmRSABits)
-		if nil != err {
-			return
-		}
-		rsaPublicKey = rsaPrivateKey.Public()
+		privateKey, err = rsa.GenerateKey(randReader, GenerateKeyAlgorithmRSABits)
+	default:
+		err = fmt.Errorf("%w: \"%s\"... must be one of \"%s\" or \"%s\"", ErrUnsupportedKeyAlgorithm, generateKeyAlgorithm, GenerateKeyAlgorithmEd25519, GenerateKeyAlgorithmRSA)
+	}
+
+	return
+}
+
+// genPrivateKeyContext is genPrivateKey() abandoned upon ctx being cancelled.
+// As key generation itself cannot be interrupted, an abandoned key generation
+// runs to completion in the background with its result discarded.
+//
+func genPrivateKeyContext(ctx context.Context, generateKeyAlgorithm string, randReader io.Reader) (privateKey crypto.Signer, err error) {
+	type genPrivateKeyResultStruct struct {
+		privateKey crypto.Signer
+		err        error
+	}
+
+	var (
+		genPrivateKeyResult     genPrivateKeyResultStruct
+		genPrivateKeyResultChan chan genPrivateKeyResultStruct
+	)
+
+	err = checkContext(ctx)
+	if nil != err {
+		return
+	}
+
+	genPrivateKeyResultChan = make(chan genPrivateKeyResultStruct, 1)
+
+	go func() {
+		var (
+			genPrivateKeyResult genPrivateKeyResultStruct
+		)
+
+		genPrivateKeyResult.privateKey, genPrivateKeyResult.err = genPrivateKey(generateKeyAlgorithm, randReader)
+
+		genPrivateKeyResultChan <- genPrivateKeyResult
+	}()
+
+	select {
+	case genPrivateKeyResult = <-genPrivateKeyResultChan:
+		privateKey, err = genPrivateKeyResult.privateKey, genPrivateKeyResult.err
+	case <-ctx.Done():
+		err = checkContext(ctx)
+	}
+
+	return
+}
+
+// checkContext returns an error wrapping ctx.Err() if ctx has been cancelled.
+//
+func checkContext(ctx context.Context) (err error) {
+	err = ctx.Err()
+	if nil != err {
+		err = fmt.Errorf("certificate generation abandoned: %w", err)
+	}
+
+	return
+}
+
+// writeCertAndKeyFiles writes the PEM-encoded Certificate and private key such
+// that a crash never leaves a truncated file behind. Each file is first written
+// to a temporary file in the destination directory, fsync'd, and then moved into
+// place. The private key file (or the combined file) is installed first and, unless
+// overwriteKey is set, is refused if it already exists. Should installing a
+// separate certFile then fail, keyFile is rolled back (restoring any private key
+// it replaced) so that the new key is never left beside the old Certificate. If
+// pkcs8PrivateKey is nil, only certFile is written.
+//
+func writeCertAndKeyFiles(x509Certificate []byte, pkcs8PrivateKey []byte, certFile string, keyFile string, overwriteKey bool) (err error) {
+	var (
+		certPEM        []byte
+		certTmpFile    string
+		keyPEM         []byte
+		keyTmpFile     string
+		oldKeyFileInfo os.FileInfo
+		oldKeyPEM      []byte
+		rollbackErr    error
+	)
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: x509Certificate})
 
-		caX509Certificate, err = x509.CreateCertificate(rand.Reader, caX509CertificateTemplate, caX509CertificateTemplate, rsaPublicKey, rsaPrivateKey)
+	if nil == pkcs8PrivateKey {
+		certTmpFile, err = writeTmpFile(certFile, certPEM, GeneratedFilePerm)
 		if nil != err {
 			return
 		}
-
-		pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(rsaPrivateKey)
+		err = installTmpFile(certTmpFile, certFile, true)
 		if nil != err {
-			return
+			_ = os.Remove(certTmpFile)
 		}
-	default:
-		err = fmt.Errorf("generateKeyAlgorithm \"%s\" not supported... must be one of \"%s\" or \"%s\"", generateKeyAlgorithm, GenerateKeyAlgorithmEd25519, GenerateKeyAlgorithmRSA)
 		return
 	}
 
-	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caX509Certificate})
 	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8PrivateKey})
 
 	if certFile == keyFile {
-		err = ioutil.WriteFile(certFile, append(certPEM, keyPEM...), GeneratedFilePerm)
+		keyTmpFile, err = writeTmpFile(keyFile, append(certPEM, keyPEM...), GeneratedKeyFilePerm)
 		if nil != err {
 			return
 		}
 	} else {
-		err = ioutil.WriteFile(certFile, certPEM, GeneratedFilePerm)
+		// Retain any private key about to be replaced should certFile fail to install
+
+		if overwriteKey {
+			oldKeyFileInfo, err = os.Stat(keyFile)
+			if nil == err {
+				oldKeyPEM, err = ioutil.ReadFile(keyFile)
+				if nil != err {
+					return
+				}
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return
+			}
+		}
+
+		keyTmpFile, err = writeTmpFile(keyFile, keyPEM, GeneratedKeyFilePerm)
+		if nil != err {
+			return
+		}
+		certTmpFile, err = writeTmpFile(certFile, certPEM, GeneratedFilePerm)
 		if nil != err {
+			_ = os.Remove(keyTmpFile)
 			return
 		}
-		err = ioutil.WriteFile(keyFile, keyPEM, GeneratedFilePerm)
+	}
+
+	err = installTmpFile(keyTmpFile, keyFile, overwriteKey)
+	if nil != err {
+		_ = os.Remove(keyTmpFile)
+		if "" != certTmpFile {
+			_ = os.Remove(certTmpFile)
+		}
+		return
+	}
+
+	if "" != certTmpFile {
+		err = installTmpFile(certTmpFile, certFile, true)
 		if nil != err {
+			_ = os.Remove(certTmpFile)
+
+			rollbackErr = rollbackKeyFile(keyFile, oldKeyFileInfo, oldKeyPEM)
+			if nil != rollbackErr {
+				err = fmt.Errorf("%w (and rolling back key file \"%s\" failed: %v)", err, keyFile, rollbackErr)
+			}
+
 			return
 		}
 	}
@@ -116,112 +1368,138 @@ func genCACert(generateKeyAlgorithm string, subject pkix.Name, ttl time.Duration
 	return
 }
 
-func genEndpointCert(generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, ttl time.Duration, caCertFile string, caKeyFile string, endpointCertFile string, endpointKeyFile string) (err error) {
+// rollbackKeyFile restores keyFile to oldKeyPEM (with the mode of
+// oldKeyFileInfo) or, if oldKeyFileInfo is nil (i.e. keyFile did not previously
+// exist), removes it.
+//
+func rollbackKeyFile(keyFile string, oldKeyFileInfo os.FileInfo, oldKeyPEM []byte) (err error) {
 	var (
-		caTLSCertificate        tls.Certificate
-		caX509Certificate       *x509.Certificate
-		certPEM                 []byte
-		ed25519PrivateKey       ed25519.PrivateKey
-		ed25519PublicKey        ed25519.PublicKey
-		keyPEM                  []byte
-		pkcs8PrivateKey         []byte
-		rsaPrivateKey           *rsa.PrivateKey
-		rsaPublicKey            crypto.PublicKey
-		serialNumber            *big.Int
-		serialNumberMax         *big.Int
-		timeNow                 time.Time
-		x509Certificate         []byte
-		x509CertificateTemplate *x509.Certificate
+		keyTmpFile string
 	)
 
-	serialNumberMax = big.NewInt(0)
-	_ = serialNumberMax.Exp(big.NewInt(2), big.NewInt(CertificateSerialNumberRandomBits), nil)
+	if nil == oldKeyFileInfo {
+		err = os.Remove(keyFile)
+		return
+	}
 
-	serialNumber, err = rand.Int(rand.Reader, serialNumberMax)
+	keyTmpFile, err = writeTmpFile(keyFile, oldKeyPEM, oldKeyFileInfo.Mode().Perm())
 	if nil != err {
 		return
 	}
 
-	timeNow = time.Now()
-
-	x509CertificateTemplate = &x509.Certificate{
-		SerialNumber:          serialNumber,
-		Subject:               subject,
-		DNSNames:              dnsNames,
-		IPAddresses:           ipAddresses,
-		NotBefore:             timeNow,
-		NotAfter:              timeNow.Add(ttl),
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:              x509.KeyUsageDigitalSignature,
-		BasicConstraintsValid: true,
+	err = installTmpFile(keyTmpFile, keyFile, true)
+	if nil != err {
+		_ = os.Remove(keyTmpFile)
 	}
 
-	caTLSCertificate, err = tls.LoadX509KeyPair(caCertFile, caKeyFile)
+	return
+}
+
+// writeTmpFile writes data to a newly created temporary file in the same directory
+// as path, sets its mode to perm, and fsync's it. The name of the temporary file
+// is returned. On failure, no temporary file is left behind.
+//
+func writeTmpFile(path string, data []byte, perm os.FileMode) (tmpPath string, err error) {
+	var (
+		tmpFile *os.File
+	)
+
+	tmpFile, err = ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
 	if nil != err {
 		return
 	}
 
-	caX509Certificate, err = x509.ParseCertificate(caTLSCertificate.Certificate[0])
+	tmpPath = tmpFile.Name()
+
+	_, err = tmpFile.Write(data)
+	if nil == err {
+		err = tmpFile.Chmod(perm)
+	}
+	if nil == err {
+		err = tmpFile.Sync()
+	}
+	if nil == err {
+		err = tmpFile.Close()
+	} else {
+		_ = tmpFile.Close()
+	}
 	if nil != err {
+		_ = os.Remove(tmpPath)
+		tmpPath = ""
+	}
+
+	return
+}
+
+// installTmpFile moves tmpPath to path. If overwrite is not set and path already
+// exists, an error wrapping os.ErrExist is returned and tmpPath remains in place
+// for the caller to remove. On a filesystem lacking hard links, the refusal to
+// overwrite instead relies on an O_EXCL create of path into which tmpPath is
+// copied, so that a crash part way through may leave path truncated.
+//
+func installTmpFile(tmpPath string, path string, overwrite bool) (err error) {
+	if overwrite {
+		err = os.Rename(tmpPath, path)
 		return
 	}
 
-	switch generateKeyAlgorithm {
-	case GenerateKeyAlgorithmEd25519:
-		ed25519PublicKey, ed25519PrivateKey, err = ed25519.GenerateKey(rand.Reader)
-		if nil != err {
-			return
-		}
+	// os.Link() refuses to replace an existing path, closing the window a
+	// separate existence check followed by os.Rename() would leave open
 
-		x509Certificate, err = x509.CreateCertificate(rand.Reader, x509CertificateTemplate, caX509Certificate, ed25519PublicKey, caTLSCertificate.PrivateKey)
-		if nil != err {
-			return
+	err = os.Link(tmpPath, path)
+	if (nil != err) && !errors.Is(err, os.ErrExist) {
+		err = copyTmpFileExclusive(tmpPath, path)
+	}
+	if nil != err {
+		if errors.Is(err, os.ErrExist) {
+			err = fmt.Errorf("refusing to overwrite existing key file \"%s\": %w", path, os.ErrExist)
 		}
+		return
+	}
 
-		pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(ed25519PrivateKey)
-		if nil != err {
-			return
-		}
-	case GenerateKeyAlgorithmRSA:
-		rsaPrivateKey, err = rsa.GenerateKey(rand.Reader, GenerateKeyAlgorithmRSABits)
-		if nil != err {
-			return
-		}
-		rsaPublicKey = rsaPrivateKey.Public()
+	err = os.Remove(tmpPath)
 
-		x509Certificate, err = x509.CreateCertificate(rand.Reader, x509CertificateTemplate, caX509Certificate, rsaPublicKey, caTLSCertificate.PrivateKey)
-		if nil != err {
-			return
-		}
+	return
+}
 
-		pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(rsaPrivateKey)
-		if nil != err {
-			return
-		}
-	default:
-		err = fmt.Errorf("generateKeyAlgorithm \"%s\" not supported... must be one of \"%s\" or \"%s\"", generateKeyAlgorithm, GenerateKeyAlgorithmEd25519, GenerateKeyAlgorithmRSA)
+// copyTmpFileExclusive is the fallback of installTmpFile() for when os.Link()
+// fails (e.g. on a filesystem lacking hard links), creating path with O_EXCL
+// (and the mode of tmpPath) and copying tmpPath into it. Should the copy fail,
+// path is removed.
+//
+func copyTmpFileExclusive(tmpPath string, path string) (err error) {
+	var (
+		data        []byte
+		file        *os.File
+		tmpFileInfo os.FileInfo
+	)
+
+	tmpFileInfo, err = os.Stat(tmpPath)
+	if nil != err {
+		return
+	}
+	data, err = ioutil.ReadFile(tmpPath)
+	if nil != err {
 		return
 	}
 
-	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: x509Certificate})
-	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8PrivateKey})
+	file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, tmpFileInfo.Mode().Perm())
+	if nil != err {
+		return
+	}
 
-	if endpointCertFile == endpointKeyFile {
-		err = ioutil.WriteFile(endpointCertFile, append(certPEM, keyPEM...), GeneratedFilePerm)
-		if nil != err {
-			return
-		}
+	_, err = file.Write(data)
+	if nil == err {
+		err = file.Sync()
+	}
+	if nil == err {
+		err = file.Close()
 	} else {
-		err = ioutil.WriteFile(endpointCertFile, certPEM, GeneratedFilePerm)
-		if nil != err {
-			return
-		}
-		err = ioutil.WriteFile(endpointKeyFile, keyPEM, GeneratedFilePerm)
-		if nil != err {
-			return
-		}
+		_ = file.Close()
+	}
+	if nil != err {
+		_ = os.Remove(path)
 	}
 
-	err = nil
 	return
 }
@@ -0,0 +1,74 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+)
+
+// subjectPublicKeyInfo is the ASN.1 structure of a DER-encoded public key as
+// returned by x509.MarshalPKIXPublicKey().
+//
+type subjectPublicKeyInfo struct {
+	Algorithm        pkix.AlgorithmIdentifier
+	SubjectPublicKey asn1.BitString
+}
+
+// subjectKeyID returns the Subject Key Identifier of publicKey computed per
+// method (1) of RFC 5280 section 4.2.1.2 (i.e. the SHA-1 hash of the
+// subjectPublicKey BIT STRING of its SubjectPublicKeyInfo).
+//
+func subjectKeyID(publicKey crypto.PublicKey) (ski []byte, err error) {
+	var (
+		publicKeyDER []byte
+		publicKeySHA [sha1.Size]byte
+		rest         []byte
+		spki         subjectPublicKeyInfo
+	)
+
+	publicKeyDER, err = x509.MarshalPKIXPublicKey(publicKey)
+	if nil != err {
+		return
+	}
+
+	rest, err = asn1.Unmarshal(publicKeyDER, &spki)
+	if nil != err {
+		return
+	}
+	if 0 != len(rest) {
+		err = fmt.Errorf("trailing data following SubjectPublicKeyInfo")
+		return
+	}
+
+	publicKeySHA = sha1.Sum(spki.SubjectPublicKey.Bytes)
+
+	ski = publicKeySHA[:]
+
+	return
+}
+
+func extractSKI(certPEMPath string) (ski []byte, err error) {
+	var (
+		x509Certificates []*x509.Certificate
+	)
+
+	x509Certificates, err = loadCertChain(certPEMPath)
+	if nil != err {
+		return
+	}
+
+	if 0 == len(x509Certificates[0].SubjectKeyId) {
+		err = fmt.Errorf("Certificate in \"%s\" has no Subject Key Identifier", certPEMPath)
+		return
+	}
+
+	ski = x509Certificates[0].SubjectKeyId
+
+	return
+}
@@ -0,0 +1,280 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// genKey returns a newly generated private key using generateKeyAlgorithm.
+//
+func genKey(generateKeyAlgorithm string) (privateKey crypto.PrivateKey, err error) {
+	var (
+		signer crypto.Signer
+	)
+
+	signer, err = genPrivateKey(generateKeyAlgorithm, rand.Reader)
+	if nil != err {
+		return
+	}
+
+	privateKey = signer
+
+	return
+}
+
+// saveKeyPEM writes key to outPath in the PEM encoding specific to its algorithm
+// (see encodeKeyPEM()). As with a generated private key file, outPath is written
+// atomically with mode GeneratedKeyFilePerm and an existing outPath is refused.
+//
+func saveKeyPEM(key crypto.PrivateKey, outPath string) (err error) {
+	var (
+		keyPEM []byte
+	)
+
+	keyPEM, err = encodeKeyPEM(key)
+	if nil != err {
+		return
+	}
+
+	err = writeKeyPEMFile(keyPEM, outPath)
+
+	return
+}
+
+// saveKeyPEMPKCS8 is saveKeyPEM() but always using the PKCS#8 ("PRIVATE KEY")
+// encoding.
+//
+func saveKeyPEMPKCS8(key crypto.PrivateKey, outPath string) (err error) {
+	var (
+		pkcs8PrivateKey []byte
+	)
+
+	pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(key)
+	if nil != err {
+		err = fmt.Errorf("private key of type %T not supported: %v", key, err)
+		return
+	}
+
+	err = writeKeyPEMFile(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8PrivateKey}), outPath)
+
+	return
+}
+
+// writeKeyPEMFile atomically writes keyPEM to outPath with mode
+// GeneratedKeyFilePerm refusing an existing outPath.
+//
+func writeKeyPEMFile(keyPEM []byte, outPath string) (err error) {
+	var (
+		keyTmpFile string
+	)
+
+	keyTmpFile, err = writeTmpFile(outPath, keyPEM, GeneratedKeyFilePerm)
+	if nil != err {
+		return
+	}
+
+	err = installTmpFile(keyTmpFile, outPath, false)
+	if nil != err {
+		_ = os.Remove(keyTmpFile)
+		return
+	}
+
+	return
+}
+
+// encodeKeyPEM returns the PEM encoding of key specific to its algorithm: PKCS#1
+// ("RSA PRIVATE KEY") for RSA keys, SEC 1 ("EC PRIVATE KEY") for ECDSA keys, and
+// PKCS#8 ("PRIVATE KEY") for Ed25519 keys (which have no algorithm-specific form).
+//
+func encodeKeyPEM(key crypto.PrivateKey) (keyPEM []byte, err error) {
+	var (
+		pemBlock *pem.Block
+	)
+
+	switch typedKey := key.(type) {
+	case *rsa.PrivateKey:
+		pemBlock = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(typedKey)}
+	case *ecdsa.PrivateKey:
+		pemBlock = &pem.Block{Type: "EC PRIVATE KEY"}
+		pemBlock.Bytes, err = x509.MarshalECPrivateKey(typedKey)
+	case ed25519.PrivateKey:
+		pemBlock = &pem.Block{Type: "PRIVATE KEY"}
+		pemBlock.Bytes, err = x509.MarshalPKCS8PrivateKey(typedKey)
+	default:
+		err = fmt.Errorf("private key of type %T not supported", key)
+	}
+	if nil != err {
+		return
+	}
+
+	keyPEM = pem.EncodeToMemory(pemBlock)
+
+	return
+}
+
+// loadKeyPEM returns the first private key found in keyPEMPath (see
+// loadPrivateKey()).
+//
+func loadKeyPEM(keyPEMPath string) (privateKey crypto.PrivateKey, err error) {
+	var (
+		signer crypto.Signer
+	)
+
+	signer, err = loadPrivateKey(keyPEMPath)
+	if nil != err {
+		return
+	}
+
+	privateKey = signer
+
+	return
+}
+
+// loadKeyPEMPKCS8 returns the first PKCS#8 ("PRIVATE KEY") private key found
+// in keyPEMPath. Other PEM blocks (including algorithm-specific private keys)
+// are skipped.
+//
+func loadKeyPEMPKCS8(keyPEMPath string) (privateKey crypto.PrivateKey, err error) {
+	var (
+		blockIndex int
+		keyPEM     []byte
+		ok         bool
+		pemBlock   *pem.Block
+	)
+
+	keyPEM, err = ioutil.ReadFile(keyPEMPath)
+	if nil != err {
+		return
+	}
+
+	for blockIndex = 0; ; blockIndex++ {
+		pemBlock, keyPEM = pem.Decode(keyPEM)
+		if nil == pemBlock {
+			err = &ErrBadPEM{Path: keyPEMPath, BlockIndex: -1, Err: errors.New("no PKCS#8 (\"PRIVATE KEY\") private key found")}
+			return
+		}
+
+		if "PRIVATE KEY" != pemBlock.Type {
+			continue
+		}
+
+		privateKey, err = x509.ParsePKCS8PrivateKey(pemBlock.Bytes)
+		if nil != err {
+			err = &ErrBadPEM{Path: keyPEMPath, BlockIndex: blockIndex, Err: fmt.Errorf("unable to parse %s: %v", pemBlock.Type, err)}
+			return
+		}
+
+		_, ok = privateKey.(crypto.Signer)
+		if !ok {
+			privateKey = nil
+			err = fmt.Errorf("private key in \"%s\" cannot be used for signing", keyPEMPath)
+			return
+		}
+
+		return
+	}
+}
+
+func (errBadPEM *ErrBadPEM) error() string {
+	if errBadPEM.BlockIndex < 0 {
+		return fmt.Sprintf("%v in \"%s\"", errBadPEM.Err, errBadPEM.Path)
+	}
+
+	return fmt.Sprintf("PEM block %d in \"%s\": %v", errBadPEM.BlockIndex, errBadPEM.Path, errBadPEM.Err)
+}
+
+// loadKeyPair is tls.LoadX509KeyPair() but tolerant of how certFile and keyFile
+// (which may be the same combined file) were laid out (see parseKeyPair()).
+//
+func loadKeyPair(certFile string, keyFile string) (tlsCertificate tls.Certificate, err error) {
+	var (
+		certPEM []byte
+		keyPEM  []byte
+	)
+
+	certPEM, err = ioutil.ReadFile(certFile)
+	if nil != err {
+		return
+	}
+	if keyFile == certFile {
+		keyPEM = certPEM
+	} else {
+		keyPEM, err = ioutil.ReadFile(keyFile)
+		if nil != err {
+			return
+		}
+	}
+
+	tlsCertificate, err = parseKeyPair(certPEM, certFile, keyPEM, keyFile)
+
+	return
+}
+
+// parseKeyPair is tls.X509KeyPair() for the PEM content certPEM and keyPEM read
+// from certFile and keyFile but tolerant of their layout. Every PEM block is
+// scanned (ignoring any non-PEM text and line ending differences) with blocks
+// of unexpected types skipped. The first private key in keyPEM (see
+// parsePrivateKey()) is paired with the Certificate in certPEM issued for it
+// (not necessarily the first) which becomes the leaf followed by the remaining
+// Certificates (see orderCertChainFrom()). Bad PEM content yields an *ErrBadPEM
+// and no Certificate matching the private key ErrKeyCertMismatch.
+//
+func parseKeyPair(certPEM []byte, certFile string, keyPEM []byte, keyFile string) (tlsCertificate tls.Certificate, err error) {
+	var (
+		found            bool
+		leafIndex        int
+		privateKey       crypto.Signer
+		publicKey        interface{ Equal(crypto.PublicKey) bool }
+		x509Certificate  *x509.Certificate
+		x509Certificates []*x509.Certificate
+	)
+
+	x509Certificates, err = parseCertChain(certPEM, certFile)
+	if nil != err {
+		return
+	}
+	privateKey, err = parsePrivateKey(keyPEM, keyFile)
+	if nil != err {
+		return
+	}
+
+	publicKey, _ = privateKey.Public().(interface{ Equal(crypto.PublicKey) bool })
+
+	found = false
+	if nil != publicKey {
+		for leafIndex = range x509Certificates {
+			if publicKey.Equal(x509Certificates[leafIndex].PublicKey) {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		err = fmt.Errorf("%w: no Certificate in \"%s\" was issued for the private key in \"%s\"", ErrKeyCertMismatch, certFile, keyFile)
+		return
+	}
+
+	x509Certificates = orderCertChainFrom(x509Certificates, leafIndex)
+
+	tlsCertificate.Certificate = make([][]byte, 0, len(x509Certificates))
+	for _, x509Certificate = range x509Certificates {
+		tlsCertificate.Certificate = append(tlsCertificate.Certificate, x509Certificate.Raw)
+	}
+	tlsCertificate.PrivateKey = privateKey
+	tlsCertificate.Leaf = x509Certificates[0]
+
+	return
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+const (
+	lintMaxSerialNumberOctets = 20
+)
+
+func (lintError *LintError) error() string {
+	var (
+		violationStrings []string
+	)
+
+	violationStrings = make([]string, 0, len(lintError.Violations))
+
+	for _, violation := range lintError.Violations {
+		violationStrings = append(violationStrings, fmt.Sprintf("[%s] %s", violation.ID, violation.Message))
+	}
+
+	return "Certificate failed lint checks: " + strings.Join(violationStrings, "; ")
+}
+
+func lintCertTemplate(x509CertificateTemplate *x509.Certificate) (violations []LintViolation) {
+	var (
+		extKeyUsage  x509.ExtKeyUsage
+		isServerAuth bool
+	)
+
+	violations = make([]LintViolation, 0)
+
+	if (nil == x509CertificateTemplate.SerialNumber) || (x509CertificateTemplate.SerialNumber.Sign() <= 0) {
+		violations = append(violations, LintViolation{LintIDSerialNumberEncoding, "SerialNumber must be positive"})
+	} else if (x509CertificateTemplate.SerialNumber.BitLen()/8)+1 > lintMaxSerialNumberOctets {
+		violations = append(violations, LintViolation{LintIDSerialNumberEncoding, fmt.Sprintf("SerialNumber must encode in at most %d octets", lintMaxSerialNumberOctets)})
+	}
+
+	switch x509CertificateTemplate.SignatureAlgorithm {
+	case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		violations = append(violations, LintViolation{LintIDSHA1Signature, fmt.Sprintf("SignatureAlgorithm %v uses SHA-1", x509CertificateTemplate.SignatureAlgorithm)})
+	}
+
+	if !x509CertificateTemplate.IsCA {
+		isServerAuth = (0 == len(x509CertificateTemplate.ExtKeyUsage))
+		for _, extKeyUsage = range x509CertificateTemplate.ExtKeyUsage {
+			if (x509.ExtKeyUsageServerAuth == extKeyUsage) || (x509.ExtKeyUsageAny == extKeyUsage) {
+				isServerAuth = true
+			}
+		}
+
+		if isServerAuth && (x509CertificateTemplate.NotAfter.Sub(x509CertificateTemplate.NotBefore) > LintMaxServerAuthValidity) {
+			violations = append(violations, LintViolation{LintIDValidityTooLong, fmt.Sprintf("validity of %v exceeds %v", x509CertificateTemplate.NotAfter.Sub(x509CertificateTemplate.NotBefore), LintMaxServerAuthValidity)})
+		}
+
+		if ("" != x509CertificateTemplate.Subject.CommonName) &&
+			(0 == len(x509CertificateTemplate.DNSNames)) &&
+			(0 == len(x509CertificateTemplate.IPAddresses)) &&
+			(0 == len(x509CertificateTemplate.EmailAddresses)) &&
+			(0 == len(x509CertificateTemplate.URIs)) {
+			violations = append(violations, LintViolation{LintIDCommonNameWithoutSAN, fmt.Sprintf("Subject.CommonName \"%s\" is set but there are no Subject Alternative Names", x509CertificateTemplate.Subject.CommonName)})
+		}
+	}
+
+	return
+}
+
+func lintCert(certFile string) (violations []LintViolation, err error) {
+	var (
+		x509Certificate *x509.Certificate
+	)
+
+	x509Certificate, err = loadFirstCert(certFile)
+	if nil != err {
+		return
+	}
+
+	violations = lintCertTemplate(x509Certificate)
+
+	return
+}
+
+// lintGeneratedCert is called prior to writing a generated Certificate. Violations
+// not allowlisted by options.LintAllow either fail generation with a *LintError or,
+// if options.LintWarn is set, are passed to it.
+//
+func lintGeneratedCert(x509CertificateDER []byte, options *CertOptions) (err error) {
+	var (
+		allowed            bool
+		lintID             LintID
+		reportedViolations []LintViolation
+		x509Certificate    *x509.Certificate
+	)
+
+	x509Certificate, err = x509.ParseCertificate(x509CertificateDER)
+	if nil != err {
+		return
+	}
+
+	reportedViolations = make([]LintViolation, 0)
+
+	for _, violation := range lintCertTemplate(x509Certificate) {
+		allowed = false
+		for _, lintID = range options.LintAllow {
+			if lintID == violation.ID {
+				allowed = true
+			}
+		}
+		if !allowed {
+			reportedViolations = append(reportedViolations, violation)
+		}
+	}
+
+	if 0 == len(reportedViolations) {
+		err = nil
+	} else if nil != options.LintWarn {
+		options.LintWarn(reportedViolations)
+		err = nil
+	} else {
+		err = &LintError{Violations: reportedViolations}
+	}
+
+	return
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockPollInterval is how often a held lock is retried while awaiting it.
+//
+const lockPollInterval = 10 * time.Millisecond
+
+// errLockWouldBlock is returned by tryLockFile() when the lock is held elsewhere.
+//
+var errLockWouldBlock = errors.New("lock held")
+
+func (errLockHeld *ErrLockHeld) error() string {
+	if 0 == errLockHeld.PID {
+		return fmt.Sprintf("lock file \"%s\" held by another generator", errLockHeld.LockFile)
+	}
+
+	return fmt.Sprintf("lock file \"%s\" held by PID %d", errLockHeld.LockFile, errLockHeld.PID)
+}
+
+func (options *CertOptions) lockOutputs(ctx context.Context, certFile string) (unlock func(), err error) {
+	var (
+		deadline    time.Time
+		file        *os.File
+		lockFile    string
+		lockTimeout time.Duration
+	)
+
+	if !options.Lock {
+		unlock = func() {}
+		return
+	}
+
+	lockTimeout = options.LockTimeout
+	if time.Duration(0) == lockTimeout {
+		lockTimeout = DefaultLockTimeout
+	}
+
+	lockFile = certFile + LockFileSuffix
+
+	file, err = os.OpenFile(lockFile, os.O_RDWR|os.O_CREATE, GeneratedFilePerm)
+	if nil != err {
+		return
+	}
+
+	deadline = time.Now().Add(lockTimeout)
+
+	for {
+		err = tryLockFile(file)
+		if nil == err {
+			break
+		}
+		if (errLockWouldBlock != err) || time.Now().After(deadline) {
+			_ = file.Close()
+			if errLockWouldBlock == err {
+				err = &ErrLockHeld{LockFile: lockFile, PID: readLockPID(lockFile)}
+			}
+			return
+		}
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			_ = file.Close()
+			err = checkContext(ctx)
+			return
+		}
+	}
+
+	// Record our PID so that a waiter that times out can report it
+
+	if nil == file.Truncate(0) {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+
+	unlock = func() {
+		_ = file.Truncate(0)
+		_ = unlockFile(file)
+		_ = file.Close()
+	}
+
+	return
+}
+
+func readLockPID(lockFile string) (pid int) {
+	var (
+		err       error
+		pidBuf    []byte
+		parsedPID int
+	)
+
+	pidBuf, err = ioutil.ReadFile(lockFile)
+	if nil != err {
+		return
+	}
+
+	parsedPID, err = strconv.Atoi(strings.TrimSpace(string(pidBuf)))
+	if (nil != err) || (parsedPID <= 0) {
+		return
+	}
+
+	pid = parsedPID
+
+	return
+}
+
+func (options *CertOptions) skipIfValid(certFile string, keyFile string, issuer *x509.Certificate) (skip bool) {
+	var (
+		err             error
+		tlsCertificate  tls.Certificate
+		x509Certificate *x509.Certificate
+	)
+
+	if options.SkipIfValidFor <= time.Duration(0) {
+		return
+	}
+
+	if ("" == keyFile) && ("" != options.ExistingKeyFile) {
+		keyFile = options.ExistingKeyFile
+	}
+
+	tlsCertificate, err = loadKeyPair(certFile, keyFile)
+	if nil != err {
+		return
+	}
+
+	x509Certificate, err = x509.ParseCertificate(tlsCertificate.Certificate[0])
+	if nil != err {
+		return
+	}
+
+	if options.now().Add(options.SkipIfValidFor).After(x509Certificate.NotAfter) {
+		return
+	}
+
+	if nil == issuer {
+		err = x509Certificate.CheckSignature(x509Certificate.SignatureAlgorithm, x509Certificate.RawTBSCertificate, x509Certificate.Signature)
+	} else {
+		err = x509Certificate.CheckSignatureFrom(issuer)
+	}
+	if nil != err {
+		return
+	}
+
+	if nil != options.OnSkip {
+		options.OnSkip()
+	}
+
+	skip = true
+
+	return
+}
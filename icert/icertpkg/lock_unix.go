@@ -0,0 +1,27 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+// +build !windows
+
+package icertpkg
+
+import (
+	"os"
+	"syscall"
+)
+
+func tryLockFile(file *os.File) (err error) {
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if syscall.EWOULDBLOCK == err {
+		err = errLockWouldBlock
+	}
+
+	return
+}
+
+func unlockFile(file *os.File) (err error) {
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+
+	return
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFileOffsetHigh places the locked byte range well beyond the PID recorded
+// in the lock file so that the (mandatory) windows lock does not block reading it.
+//
+const lockFileOffsetHigh = 0x7FFFFFFF
+
+func tryLockFile(file *os.File) (err error) {
+	err = windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &windows.Overlapped{OffsetHigh: lockFileOffsetHigh})
+	if windows.ERROR_LOCK_VIOLATION == err {
+		err = errLockWouldBlock
+	}
+
+	return
+}
+
+func unlockFile(file *os.File) (err error) {
+	err = windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{OffsetHigh: lockFileOffsetHigh})
+
+	return
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func fingerprint(certPath string) (fingerprintString string, err error) {
+	var (
+		x509Certificate *x509.Certificate
+	)
+
+	x509Certificate, err = loadFirstCert(certPath)
+	if nil != err {
+		return
+	}
+
+	fingerprintString = certFingerprint(x509Certificate)
+
+	return
+}
+
+// certFingerprint returns the SHA-256 of the DER encoding of x509Certificate as
+// colon separated uppercase hexadecimal.
+//
+func certFingerprint(x509Certificate *x509.Certificate) string {
+	var (
+		fingerprint      [sha256.Size]byte
+		fingerprintByte  byte
+		fingerprintBytes []string
+	)
+
+	fingerprint = sha256.Sum256(x509Certificate.Raw)
+	fingerprintBytes = make([]string, 0, len(fingerprint))
+	for _, fingerprintByte = range fingerprint {
+		fingerprintBytes = append(fingerprintBytes, fmt.Sprintf("%02X", fingerprintByte))
+	}
+
+	return strings.Join(fingerprintBytes, ":")
+}
+
+// parseFingerprint returns the SHA-256 given as hexadecimal (in either case and
+// optionally colon separated) by fingerprintString.
+//
+func parseFingerprint(fingerprintString string) (fingerprint []byte, err error) {
+	fingerprint, err = hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(fingerprintString), ":", ""))
+	if (nil != err) || (sha256.Size != len(fingerprint)) {
+		fingerprint = nil
+		err = fmt.Errorf("fingerprint \"%s\" invalid... must be a SHA-256 in hexadecimal", fingerprintString)
+		return
+	}
+
+	return
+}
+
+func buildPinnedClientTLSConfig(fingerprints []string) (tlsConfig *tls.Config, err error) {
+	var (
+		fingerprintString string
+		pin               []byte
+		pins              [][]byte
+	)
+
+	if 0 == len(fingerprints) {
+		err = fmt.Errorf("at least one fingerprint must be pinned")
+		return
+	}
+
+	pins = make([][]byte, 0, len(fingerprints))
+
+	for _, fingerprintString = range fingerprints {
+		pin, err = parseFingerprint(fingerprintString)
+		if nil != err {
+			return
+		}
+		pins = append(pins, pin)
+	}
+
+	tlsConfig = hardenedTLSConfig(&tls.Config{
+		InsecureSkipVerify: true, // Replaced by verifyPinnedPeerCertificate()
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyPinnedPeerCertificate(pins, rawCerts, time.Now())
+		},
+	}, 0, nil)
+
+	return
+}
+
+// verifyPinnedPeerCertificate accepts the leaf (first) of rawCerts only if valid
+// at timeNow and its SHA-256 fingerprint is one of pins. Any other Certificates
+// presented are ignored.
+//
+func verifyPinnedPeerCertificate(pins [][]byte, rawCerts [][]byte, timeNow time.Time) (err error) {
+	var (
+		fingerprint     [sha256.Size]byte
+		pin             []byte
+		x509Certificate *x509.Certificate
+	)
+
+	if 0 == len(rawCerts) {
+		err = errors.New("no Certificate presented")
+		return
+	}
+
+	x509Certificate, err = x509.ParseCertificate(rawCerts[0])
+	if nil != err {
+		return
+	}
+
+	if timeNow.Before(x509Certificate.NotBefore) {
+		err = fmt.Errorf("%w: NotBefore (%v) of \"%s\" follows %v", ErrCertNotYetValid, x509Certificate.NotBefore, x509Certificate.Subject, timeNow)
+		return
+	}
+	if timeNow.After(x509Certificate.NotAfter) {
+		err = fmt.Errorf("%w: NotAfter (%v) of \"%s\" precedes %v", ErrCertExpired, x509Certificate.NotAfter, x509Certificate.Subject, timeNow)
+		return
+	}
+
+	fingerprint = sha256.Sum256(x509Certificate.Raw)
+
+	for _, pin = range pins {
+		if bytes.Equal(pin, fingerprint[:]) {
+			return
+		}
+	}
+
+	err = fmt.Errorf("%w: \"%s\" has fingerprint %s", ErrPinMismatch, x509Certificate.Subject, certFingerprint(x509Certificate))
+
+	return
+}
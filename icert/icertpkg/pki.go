@@ -0,0 +1,309 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/NVIDIA/proxyfs/conf"
+)
+
+func loadPKISpec(confMap conf.ConfMap) (pkiSpec *PKISpec, err error) {
+	var (
+		endpointName  string
+		endpointNames []string
+		pkiCertSpec   *PKICertSpec
+	)
+
+	pkiCertSpec, endpointNames, err = loadPKICertSpec(confMap, PKICASectionName, true)
+	if nil != err {
+		return
+	}
+
+	pkiSpec = &PKISpec{
+		CA:        *pkiCertSpec,
+		Endpoints: make([]PKICertSpec, 0, len(endpointNames)),
+	}
+
+	for _, endpointName = range endpointNames {
+		pkiCertSpec, _, err = loadPKICertSpec(confMap, PKIEndpointSectionPrefix+endpointName, false)
+		if nil != err {
+			pkiSpec = nil
+			return
+		}
+
+		pkiCertSpec.Name = endpointName
+
+		pkiSpec.Endpoints = append(pkiSpec.Endpoints, *pkiCertSpec)
+	}
+
+	return
+}
+
+func loadPKICertSpec(confMap conf.ConfMap, sectionName string, isCA bool) (pkiCertSpec *PKICertSpec, endpointNames []string, err error) {
+	var (
+		ipAddress       net.IP
+		ipAddressString string
+		ok              bool
+		optionName      string
+		optionValue     conf.ConfMapOption
+		section         conf.ConfMapSection
+		stringDest      *string
+		stringSliceDest *[]string
+	)
+
+	section, ok = confMap[sectionName]
+	if !ok {
+		err = fmt.Errorf("Section '[%v]' is missing", sectionName)
+		return
+	}
+
+	pkiCertSpec = &PKICertSpec{}
+
+	for optionName, optionValue = range section {
+		stringDest = nil
+		stringSliceDest = nil
+
+		switch optionName {
+		case "Algorithm":
+			stringDest = &pkiCertSpec.GenerateKeyAlgorithm
+		case "Organization":
+			stringSliceDest = &pkiCertSpec.Subject.Organization
+		case "Country":
+			stringSliceDest = &pkiCertSpec.Subject.Country
+		case "Province":
+			stringSliceDest = &pkiCertSpec.Subject.Province
+		case "Locality":
+			stringSliceDest = &pkiCertSpec.Subject.Locality
+		case "StreetAddress":
+			stringSliceDest = &pkiCertSpec.Subject.StreetAddress
+		case "PostalCode":
+			stringSliceDest = &pkiCertSpec.Subject.PostalCode
+		case "CommonName":
+			stringDest = &pkiCertSpec.Subject.CommonName
+		case "TTL":
+			pkiCertSpec.TTL, err = confMap.FetchOptionValueDuration(sectionName, optionName)
+			if nil != err {
+				pkiCertSpec = nil
+				return
+			}
+		case "CertFile":
+			stringDest = &pkiCertSpec.CertFile
+		case "KeyFile":
+			stringDest = &pkiCertSpec.KeyFile
+		default:
+			switch {
+			case isCA && ("Endpoints" == optionName):
+				endpointNames = append([]string{}, optionValue...)
+			case !isCA && ("DNSNames" == optionName):
+				stringSliceDest = &pkiCertSpec.DNSNames
+			case !isCA && ("IPAddresses" == optionName):
+				pkiCertSpec.IPAddresses = make([]net.IP, 0, len(optionValue))
+				for _, ipAddressString = range optionValue {
+					ipAddress = net.ParseIP(ipAddressString)
+					if nil == ipAddress {
+						pkiCertSpec = nil
+						err = fmt.Errorf("Option '[%v]%v' contains invalid IP Address \"%s\"", sectionName, optionName, ipAddressString)
+						return
+					}
+					pkiCertSpec.IPAddresses = append(pkiCertSpec.IPAddresses, ipAddress)
+				}
+			case !isCA && ("EmailAddresses" == optionName):
+				stringSliceDest = &pkiCertSpec.EmailAddresses
+			case !isCA && ("URIs" == optionName):
+				stringSliceDest = &pkiCertSpec.URIs
+			default:
+				pkiCertSpec = nil
+				err = fmt.Errorf("Option '[%v]%v' is not supported", sectionName, optionName)
+				return
+			}
+		}
+
+		if nil != stringDest {
+			*stringDest, err = confMap.FetchOptionValueString(sectionName, optionName)
+			if nil != err {
+				pkiCertSpec = nil
+				return
+			}
+		}
+		if nil != stringSliceDest {
+			*stringSliceDest = append([]string{}, optionValue...)
+		}
+	}
+
+	return
+}
+
+// pkiCertSpecPaths returns the distinct output paths of pkiCertSpec.
+//
+func pkiCertSpecPaths(pkiCertSpec *PKICertSpec) (paths []string) {
+	paths = []string{filepath.Clean(pkiCertSpec.CertFile)}
+
+	if filepath.Clean(pkiCertSpec.KeyFile) != paths[0] {
+		paths = append(paths, filepath.Clean(pkiCertSpec.KeyFile))
+	}
+
+	return
+}
+
+func (pkiSpec *PKISpec) validate() (err error) {
+	var (
+		endpointNames map[string]struct{}
+		fileNames     map[string]string
+		ok            bool
+		otherCertName string
+		path          string
+		pkiCertName   string
+		pkiCertSpec   *PKICertSpec
+		pkiCertSpecs  []*PKICertSpec
+	)
+
+	if (0 != len(pkiSpec.CA.DNSNames)) || (0 != len(pkiSpec.CA.IPAddresses)) || (0 != len(pkiSpec.CA.EmailAddresses)) || (0 != len(pkiSpec.CA.URIs)) {
+		err = fmt.Errorf("CA may not specify SANs")
+		return
+	}
+
+	pkiCertSpecs = []*PKICertSpec{&pkiSpec.CA}
+	endpointNames = make(map[string]struct{})
+
+	for i := range pkiSpec.Endpoints {
+		pkiCertSpec = &pkiSpec.Endpoints[i]
+		if "" == pkiCertSpec.Name {
+			err = fmt.Errorf("Endpoints[%d] has no Name", i)
+			return
+		}
+		_, ok = endpointNames[pkiCertSpec.Name]
+		if ok {
+			err = fmt.Errorf("Endpoint \"%s\" specified more than once", pkiCertSpec.Name)
+			return
+		}
+		endpointNames[pkiCertSpec.Name] = struct{}{}
+		pkiCertSpecs = append(pkiCertSpecs, pkiCertSpec)
+	}
+
+	fileNames = make(map[string]string)
+
+	for _, pkiCertSpec = range pkiCertSpecs {
+		pkiCertName = pkiCertSpec.displayName()
+
+		if ("" == pkiCertSpec.CertFile) || ("" == pkiCertSpec.KeyFile) {
+			err = fmt.Errorf("%s must specify both CertFile and KeyFile", pkiCertName)
+			return
+		}
+
+		for _, path = range pkiCertSpecPaths(pkiCertSpec) {
+			otherCertName, ok = fileNames[path]
+			if ok {
+				err = fmt.Errorf("output path \"%s\" specified by both %s and %s", path, otherCertName, pkiCertName)
+				return
+			}
+			fileNames[path] = pkiCertName
+
+			_, err = os.Lstat(path)
+			if nil == err {
+				err = fmt.Errorf("output path \"%s\" of %s already exists: %w", path, pkiCertName, os.ErrExist)
+				return
+			}
+			if !os.IsNotExist(err) {
+				return
+			}
+		}
+	}
+
+	err = nil
+	return
+}
+
+func (pkiCertSpec *PKICertSpec) displayName() string {
+	if "" == pkiCertSpec.Name {
+		return "CA"
+	}
+
+	return fmt.Sprintf("Endpoint \"%s\"", pkiCertSpec.Name)
+}
+
+func genPKI(pkiSpec *PKISpec) (pkiResult *PKIResult, err error) {
+	var (
+		ca            *CA
+		generatedCert PKIGeneratedCert
+		path          string
+		pkiCertSpec   *PKICertSpec
+		writtenPaths  []string
+	)
+
+	err = pkiSpec.validate()
+	if nil != err {
+		return
+	}
+
+	// Every output path was just confirmed not to exist, so on failure any
+	// that now do (including those of a partially generated Certificate) were
+	// written by this call
+
+	defer func() {
+		if nil != err {
+			for _, path = range writtenPaths {
+				_ = os.Remove(path)
+			}
+			pkiResult = nil
+		}
+	}()
+
+	pkiCertSpec = &pkiSpec.CA
+	writtenPaths = append(writtenPaths, pkiCertSpecPaths(pkiCertSpec)...)
+
+	err = genCACert(context.Background(), pkiCertSpec.GenerateKeyAlgorithm, pkiCertSpec.Subject, pkiCertSpec.TTL, pkiCertSpec.CertFile, pkiCertSpec.KeyFile, nil)
+	if nil != err {
+		err = fmt.Errorf("%s generation failed: %w", pkiCertSpec.displayName(), err)
+		return
+	}
+
+	ca, err = loadCA(pkiCertSpec.CertFile, pkiCertSpec.KeyFile)
+	if nil != err {
+		return
+	}
+
+	pkiResult = &PKIResult{Endpoints: make([]PKIGeneratedCert, 0, len(pkiSpec.Endpoints))}
+
+	pkiResult.CA, err = newPKIGeneratedCert(pkiCertSpec)
+	if nil != err {
+		return
+	}
+
+	for i := range pkiSpec.Endpoints {
+		pkiCertSpec = &pkiSpec.Endpoints[i]
+		writtenPaths = append(writtenPaths, pkiCertSpecPaths(pkiCertSpec)...)
+
+		err = ca.genEndpointCert(context.Background(), pkiCertSpec.GenerateKeyAlgorithm, pkiCertSpec.Subject, pkiCertSpec.DNSNames, pkiCertSpec.IPAddresses, pkiCertSpec.EmailAddresses, pkiCertSpec.URIs, pkiCertSpec.TTL, pkiCertSpec.CertFile, pkiCertSpec.KeyFile, nil)
+		if nil != err {
+			err = fmt.Errorf("%s generation failed: %w", pkiCertSpec.displayName(), err)
+			return
+		}
+
+		generatedCert, err = newPKIGeneratedCert(pkiCertSpec)
+		if nil != err {
+			return
+		}
+
+		pkiResult.Endpoints = append(pkiResult.Endpoints, generatedCert)
+	}
+
+	return
+}
+
+func newPKIGeneratedCert(pkiCertSpec *PKICertSpec) (generatedCert PKIGeneratedCert, err error) {
+	generatedCert = PKIGeneratedCert{
+		Name:     pkiCertSpec.Name,
+		CertFile: pkiCertSpec.CertFile,
+		KeyFile:  pkiCertSpec.KeyFile,
+	}
+
+	generatedCert.Info, err = getCertInfo(pkiCertSpec.CertFile)
+
+	return
+}
@@ -0,0 +1,347 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+// certNeedsRenewal is CertNeedsRenewal() as of timeNow.
+//
+func certNeedsRenewal(certPath string, threshold time.Duration, timeNow time.Time) (needsRenewal bool, notAfter time.Time, err error) {
+	var (
+		x509Certificate *x509.Certificate
+	)
+
+	x509Certificate, err = loadFirstCert(certPath)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			needsRenewal = true
+			err = nil
+		}
+		return
+	}
+
+	notAfter = x509Certificate.NotAfter
+	needsRenewal = notAfter.Sub(timeNow) < threshold
+
+	return
+}
+
+func newRenewalWatcher(ctx context.Context, ca *CA, request GenCertRequest, options *RenewalWatcherOptions) (renewalWatcher *RenewalWatcher, err error) {
+	if nil == ca {
+		err = fmt.Errorf("ca must not be nil")
+		return
+	}
+
+	renewalWatcher = &RenewalWatcher{
+		ca:       ca,
+		request:  request,
+		stopChan: make(chan struct{}),
+	}
+
+	if nil != options {
+		renewalWatcher.options = *options
+	}
+	if renewalWatcher.options.CheckInterval <= time.Duration(0) {
+		renewalWatcher.options.CheckInterval = DefaultRenewalCheckInterval
+	}
+	if nil == renewalWatcher.options.Now {
+		renewalWatcher.options.Now = time.Now
+	}
+
+	renewalWatcher.stopWG.Add(1)
+	go renewalWatcher.watcher(ctx)
+
+	return
+}
+
+func (renewalWatcher *RenewalWatcher) close() {
+	renewalWatcher.stopOnce.Do(func() { close(renewalWatcher.stopChan) })
+	renewalWatcher.stopWG.Wait()
+}
+
+func (renewalWatcher *RenewalWatcher) watcher(ctx context.Context) {
+	var (
+		tickChan <-chan time.Time
+		ticker   *time.Ticker
+	)
+
+	defer renewalWatcher.stopWG.Done()
+
+	if nil == renewalWatcher.options.Tick {
+		ticker = time.NewTicker(renewalWatcher.options.CheckInterval)
+		defer ticker.Stop()
+		tickChan = ticker.C
+	} else {
+		tickChan = renewalWatcher.options.Tick
+	}
+
+	// Check immediately so that a missing or nearly expired Certificate need not
+	// await the first tick
+
+	renewalWatcher.check()
+
+	for {
+		select {
+		case <-renewalWatcher.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		case <-tickChan:
+			renewalWatcher.check()
+		}
+	}
+}
+
+// check re-issues the watched Certificate if it needs renewal, reporting the
+// outcome via options.OnRenewal.
+//
+func (renewalWatcher *RenewalWatcher) check() {
+	var (
+		needsRenewal bool
+		result       GenCertResult
+	)
+
+	result = GenCertResult{
+		CertFile: renewalWatcher.request.CertFile,
+		KeyFile:  renewalWatcher.request.KeyFile,
+	}
+
+	needsRenewal, _, result.Err = certNeedsRenewal(renewalWatcher.request.CertFile, renewalWatcher.options.Threshold, renewalWatcher.options.Now())
+	if (nil == result.Err) && !needsRenewal {
+		return
+	}
+
+	if nil == result.Err {
+		result.SerialNumber, result.Err = renewalWatcher.renew()
+	}
+
+	if nil != renewalWatcher.options.OnRenewal {
+		renewalWatcher.options.OnRenewal(result)
+	}
+}
+
+// renew re-issues the watched Certificate (reusing its existing private key
+// unless options.NewKey is set or no such key can be found) returning the
+// SerialNumber of the new Certificate.
+//
+func (renewalWatcher *RenewalWatcher) renew() (serialNumber *big.Int, err error) {
+	var (
+		keyFile string
+		options CertOptions
+		request *GenCertRequest
+	)
+
+	request = &renewalWatcher.request
+
+	if nil != request.Options {
+		options = *request.Options
+	}
+	if nil == options.Now {
+		options.Now = renewalWatcher.options.Now
+	}
+
+	keyFile = request.KeyFile
+	if "" == keyFile {
+		keyFile = request.CertFile
+	}
+
+	if !renewalWatcher.options.NewKey && (nil == options.ExistingKey) && ("" == options.ExistingKeyFile) {
+		options.ExistingKey, err = loadPrivateKey(keyFile)
+		if nil != err {
+			if !errors.Is(err, os.ErrNotExist) {
+				return
+			}
+			options.ExistingKey = nil // No existing key to reuse... so generate one
+		}
+	}
+
+	serialNumber, err = genSerialNumber(options.randReader())
+	if nil != err {
+		return
+	}
+
+	options.serialNumber = serialNumber
+	options.issuanceKind = IssuanceKindEndpointRenewal
+
+	err = renewalWatcher.ca.genEndpointCert(context.Background(), request.GenerateKeyAlgorithm, request.Subject, request.DNSNames, request.IPAddresses, request.EmailAddresses, request.URIs, request.TTL, request.CertFile, request.KeyFile, &options)
+	if nil != err {
+		serialNumber = nil
+	}
+
+	return
+}
+
+// caRenewalCarriedExtensionOIDs lists the OIDs, beyond those of builtInExtensions,
+// of extensions that crypto/x509 regenerates from the fields copied by
+// renewCACert() and which therefore must not also be carried as ExtraExtensions.
+//
+var caRenewalCarriedExtensionOIDs = []asn1.ObjectIdentifier{
+	{2, 5, 29, 32}, // Certificate Policies
+}
+
+func renewCACert(caCertPath string, caKeyPath string, newTTL time.Duration, outputCertPath string) (err error) {
+	var (
+		ca                        *CA
+		caX509Certificate         []byte
+		caX509CertificateTemplate *x509.Certificate
+		oldX509Certificate        *x509.Certificate
+		pkcs8PrivateKey           []byte
+		serialNumber              *big.Int
+		timeNow                   time.Time
+	)
+
+	if newTTL <= time.Duration(0) {
+		err = fmt.Errorf("newTTL (%v) must be positive", newTTL)
+		return
+	}
+
+	ca, err = readCA(caCertPath, caKeyPath)
+	if nil != err {
+		return
+	}
+
+	oldX509Certificate = ca.x509Certificate
+
+	if !oldX509Certificate.IsCA {
+		err = fmt.Errorf("%w: \"%s\" in \"%s\"", ErrNotCACert, oldX509Certificate.Subject, caCertPath)
+		return
+	}
+
+	// Only a self-signed CA Certificate may be re-signed by its own key
+
+	err = oldX509Certificate.CheckSignatureFrom(oldX509Certificate)
+	if nil != err {
+		err = fmt.Errorf("CA Certificate \"%s\" in \"%s\" is not self-signed (%v)... renew it via its issuer", oldX509Certificate.Subject, caCertPath, err)
+		return
+	}
+
+	serialNumber, err = genSerialNumber(rand.Reader)
+	if nil != err {
+		return
+	}
+
+	timeNow = time.Now()
+
+	caX509CertificateTemplate = &x509.Certificate{
+		SerialNumber:                serialNumber,
+		RawSubject:                  oldX509Certificate.RawSubject,
+		NotBefore:                   timeNow,
+		NotAfter:                    timeNow.Add(newTTL),
+		SignatureAlgorithm:          oldX509Certificate.SignatureAlgorithm,
+		KeyUsage:                    oldX509Certificate.KeyUsage,
+		ExtKeyUsage:                 oldX509Certificate.ExtKeyUsage,
+		UnknownExtKeyUsage:          oldX509Certificate.UnknownExtKeyUsage,
+		BasicConstraintsValid:       oldX509Certificate.BasicConstraintsValid,
+		IsCA:                        true,
+		MaxPathLen:                  oldX509Certificate.MaxPathLen,
+		MaxPathLenZero:              oldX509Certificate.MaxPathLenZero,
+		SubjectKeyId:                oldX509Certificate.SubjectKeyId,
+		AuthorityKeyId:              oldX509Certificate.AuthorityKeyId,
+		OCSPServer:                  oldX509Certificate.OCSPServer,
+		IssuingCertificateURL:       oldX509Certificate.IssuingCertificateURL,
+		DNSNames:                    oldX509Certificate.DNSNames,
+		EmailAddresses:              oldX509Certificate.EmailAddresses,
+		IPAddresses:                 oldX509Certificate.IPAddresses,
+		URIs:                        oldX509Certificate.URIs,
+		PermittedDNSDomainsCritical: oldX509Certificate.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:         oldX509Certificate.PermittedDNSDomains,
+		ExcludedDNSDomains:          oldX509Certificate.ExcludedDNSDomains,
+		PermittedIPRanges:           oldX509Certificate.PermittedIPRanges,
+		ExcludedIPRanges:            oldX509Certificate.ExcludedIPRanges,
+		PermittedEmailAddresses:     oldX509Certificate.PermittedEmailAddresses,
+		ExcludedEmailAddresses:      oldX509Certificate.ExcludedEmailAddresses,
+		PermittedURIDomains:         oldX509Certificate.PermittedURIDomains,
+		ExcludedURIDomains:          oldX509Certificate.ExcludedURIDomains,
+		CRLDistributionPoints:       oldX509Certificate.CRLDistributionPoints,
+		PolicyIdentifiers:           oldX509Certificate.PolicyIdentifiers,
+		ExtraExtensions:             caRenewalExtraExtensions(oldX509Certificate),
+	}
+
+	caX509Certificate, err = x509.CreateCertificate(rand.Reader, caX509CertificateTemplate, caX509CertificateTemplate, ca.signer.Public(), ca.signer)
+	if nil != err {
+		return
+	}
+
+	// Mirror a combined input (or replace a combined caKeyPath) with a combined output
+
+	if (caCertPath == caKeyPath) || (outputCertPath == caKeyPath) {
+		pkcs8PrivateKey, err = x509.MarshalPKCS8PrivateKey(ca.signer)
+		if nil != err {
+			return
+		}
+
+		err = writeCertAndKeyFiles(caX509Certificate, pkcs8PrivateKey, outputCertPath, outputCertPath, true)
+	} else {
+		err = writeCertAndKeyFiles(caX509Certificate, nil, outputCertPath, "", false)
+	}
+	if nil != err {
+		return
+	}
+
+	err = reportIssuance(IssuanceKindCARenewal, caX509Certificate, outputCertPath)
+
+	return
+}
+
+// caRenewalExtraExtensions returns the extensions of oldX509Certificate that
+// crypto/x509 would not regenerate from the fields copied by renewCACert().
+//
+func caRenewalExtraExtensions(oldX509Certificate *x509.Certificate) (extraExtensions []pkix.Extension) {
+	extraExtensions = renewalExtraExtensions(oldX509Certificate, caRenewalCarriedExtensionOIDs)
+	return
+}
+
+// endpointRenewalExtraExtensions returns the extensions of oldX509Certificate
+// that the generation of its renewal by (*CA).renewEndpointCertInStore() would
+// not regenerate. As that fills in no Certificate Policies (or other fields
+// beyond what builtInExtensions cover), only builtInExtensions are omitted.
+//
+func endpointRenewalExtraExtensions(oldX509Certificate *x509.Certificate) (extraExtensions []pkix.Extension) {
+	extraExtensions = renewalExtraExtensions(oldX509Certificate, nil)
+	return
+}
+
+// renewalExtraExtensions returns the extensions of oldX509Certificate matching
+// neither builtInExtensions nor carriedOIDs.
+//
+func renewalExtraExtensions(oldX509Certificate *x509.Certificate, carriedOIDs []asn1.ObjectIdentifier) (extraExtensions []pkix.Extension) {
+	var (
+		builtInExtension int
+		extension        pkix.Extension
+		oid              asn1.ObjectIdentifier
+		regenerated      bool
+	)
+
+	for _, extension = range oldX509Certificate.Extensions {
+		regenerated = false
+		for builtInExtension = range builtInExtensions {
+			if extension.Id.Equal(builtInExtensions[builtInExtension].oid) {
+				regenerated = true
+				break
+			}
+		}
+		for _, oid = range carriedOIDs {
+			if extension.Id.Equal(oid) {
+				regenerated = true
+				break
+			}
+		}
+		if !regenerated {
+			extraExtensions = append(extraExtensions, extension)
+		}
+	}
+
+	return
+}
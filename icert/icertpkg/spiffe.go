@@ -0,0 +1,232 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// spiffeX509SVIDUse is the "use" of a JWK holding an X.509-SVID CA.
+//
+const spiffeX509SVIDUse = "x509-svid"
+
+type spiffeBundleStruct struct {
+	Keys              []*spiffeJWKStruct `json:"keys"`
+	SPIFFESequence    uint64             `json:"spiffe_sequence,omitempty"`
+	SPIFFERefreshHint int64              `json:"spiffe_refresh_hint,omitempty"`
+}
+
+type spiffeJWKStruct struct {
+	Use string   `json:"use"`
+	Kty string   `json:"kty"`
+	Crv string   `json:"crv,omitempty"`
+	X   string   `json:"x,omitempty"`
+	Y   string   `json:"y,omitempty"`
+	N   string   `json:"n,omitempty"`
+	E   string   `json:"e,omitempty"`
+	X5c []string `json:"x5c,omitempty"`
+}
+
+func exportSPIFFEBundle(caCertPaths []string, trustDomain string, w io.Writer, options *SPIFFEBundleOptions) (err error) {
+	var (
+		caCertPath        string
+		caX509Certificate *x509.Certificate
+		jwk               *spiffeJWKStruct
+		spiffeBundle      *spiffeBundleStruct
+		spiffeBundleJSON  []byte
+		x509Certificates  []*x509.Certificate
+	)
+
+	err = validateSPIFFETrustDomain(trustDomain)
+	if nil != err {
+		return
+	}
+
+	if 0 == len(caCertPaths) {
+		err = fmt.Errorf("no caCertPaths specified")
+		return
+	}
+
+	spiffeBundle = &spiffeBundleStruct{Keys: make([]*spiffeJWKStruct, 0, len(caCertPaths))}
+
+	if nil != options {
+		spiffeBundle.SPIFFESequence = options.Sequence
+		spiffeBundle.SPIFFERefreshHint = int64(options.RefreshHint / time.Second)
+	}
+
+	for _, caCertPath = range caCertPaths {
+		x509Certificates, err = loadCertChain(caCertPath)
+		if nil != err {
+			return
+		}
+
+		for _, caX509Certificate = range x509Certificates {
+			if !caX509Certificate.IsCA {
+				err = fmt.Errorf("Certificate \"%s\" in \"%s\" is not a CA", caX509Certificate.Subject, caCertPath)
+				return
+			}
+
+			for _, uri := range caX509Certificate.URIs {
+				if ("spiffe" == uri.Scheme) && (trustDomain != uri.Host) {
+					err = fmt.Errorf("CA Certificate \"%s\" in \"%s\" has SPIFFE ID \"%s\" outside trust domain \"%s\"", caX509Certificate.Subject, caCertPath, uri, trustDomain)
+					return
+				}
+			}
+
+			jwk, err = newSPIFFEJWK(caX509Certificate)
+			if nil != err {
+				return
+			}
+
+			spiffeBundle.Keys = append(spiffeBundle.Keys, jwk)
+		}
+	}
+
+	spiffeBundleJSON, err = json.MarshalIndent(spiffeBundle, "", "  ")
+	if nil != err {
+		return
+	}
+
+	_, err = w.Write(append(spiffeBundleJSON, '\n'))
+
+	return
+}
+
+// validateSPIFFETrustDomain checks trustDomain against the SPIFFE ID
+// specification's trust domain name syntax.
+//
+func validateSPIFFETrustDomain(trustDomain string) (err error) {
+	if "" == trustDomain {
+		err = fmt.Errorf("trustDomain must not be empty")
+		return
+	}
+
+	for _, r := range trustDomain {
+		if !(('a' <= r) && (r <= 'z')) && !(('0' <= r) && (r <= '9')) && !strings.ContainsRune(".-_", r) {
+			err = fmt.Errorf("trustDomain \"%s\" may only contain lowercase letters, digits, '.', '-', and '_'", trustDomain)
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+func newSPIFFEJWK(x509Certificate *x509.Certificate) (jwk *spiffeJWKStruct, err error) {
+	jwk = &spiffeJWKStruct{
+		Use: spiffeX509SVIDUse,
+		X5c: []string{base64.StdEncoding.EncodeToString(x509Certificate.Raw)},
+	}
+
+	switch publicKey := x509Certificate.PublicKey.(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(spiffePadCoordinate(publicKey.X.Bytes(), publicKey.Curve.Params().BitSize))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(spiffePadCoordinate(publicKey.Y.Bytes(), publicKey.Curve.Params().BitSize))
+	default:
+		jwk = nil
+		err = fmt.Errorf("Certificate \"%s\" has unsupported public key type %T", x509Certificate.Subject, publicKey)
+	}
+
+	return
+}
+
+// spiffePadCoordinate left pads an ECDSA coordinate to the full size of the
+// curve as required by RFC 7518.
+//
+func spiffePadCoordinate(coordinate []byte, bitSize int) (padded []byte) {
+	padded = make([]byte, (bitSize+7)/8)
+	copy(padded[len(padded)-len(coordinate):], coordinate)
+
+	return
+}
+
+func importSPIFFEBundle(r io.Reader) (certPool *x509.CertPool, err error) {
+	var (
+		certs           int
+		expectedJWK     *spiffeJWKStruct
+		jwk             *spiffeJWKStruct
+		spiffeBundle    spiffeBundleStruct
+		x509Certificate *x509.Certificate
+		x5cDER          []byte
+	)
+
+	err = json.NewDecoder(r).Decode(&spiffeBundle)
+	if nil != err {
+		err = fmt.Errorf("SPIFFE trust bundle is not valid JSON: %v", err)
+		return
+	}
+
+	certPool = x509.NewCertPool()
+
+	for i := range spiffeBundle.Keys {
+		jwk = spiffeBundle.Keys[i]
+		if (nil == jwk) || (spiffeX509SVIDUse != jwk.Use) {
+			continue
+		}
+
+		if 1 != len(jwk.X5c) {
+			certPool = nil
+			err = fmt.Errorf("SPIFFE trust bundle keys[%d] has %d x5c entries (must be 1)", i, len(jwk.X5c))
+			return
+		}
+
+		x5cDER, err = base64.StdEncoding.DecodeString(jwk.X5c[0])
+		if nil == err {
+			x509Certificate, err = x509.ParseCertificate(x5cDER)
+		}
+		if nil != err {
+			certPool = nil
+			err = fmt.Errorf("SPIFFE trust bundle keys[%d] x5c is not a valid Certificate: %v", i, err)
+			return
+		}
+
+		if !x509Certificate.IsCA {
+			certPool = nil
+			err = fmt.Errorf("SPIFFE trust bundle keys[%d] Certificate \"%s\" is not a CA", i, x509Certificate.Subject)
+			return
+		}
+
+		expectedJWK, err = newSPIFFEJWK(x509Certificate)
+		if nil != err {
+			certPool = nil
+			return
+		}
+		if (expectedJWK.Kty != jwk.Kty) || (expectedJWK.Crv != jwk.Crv) || (expectedJWK.X != jwk.X) || (expectedJWK.Y != jwk.Y) || (expectedJWK.N != jwk.N) || (expectedJWK.E != jwk.E) {
+			certPool = nil
+			err = fmt.Errorf("SPIFFE trust bundle keys[%d] does not match the public key of its Certificate \"%s\"", i, x509Certificate.Subject)
+			return
+		}
+
+		certPool.AddCert(x509Certificate)
+		certs++
+	}
+
+	if 0 == certs {
+		certPool = nil
+		err = fmt.Errorf("SPIFFE trust bundle contains no %s keys", spiffeX509SVIDUse)
+		return
+	}
+
+	return
+}
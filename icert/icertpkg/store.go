@@ -0,0 +1,447 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+func genCACertToStore(store CertStore, generateKeyAlgorithm string, subject pkix.Name, ttl time.Duration, id string, options *CertOptions) (err error) {
+	var (
+		storeOptions *CertOptions
+	)
+
+	storeOptions, err = newStoreCertOptions(store, id, options)
+	if nil != err {
+		return
+	}
+
+	err = genCACert(context.Background(), generateKeyAlgorithm, subject, ttl, id, id, storeOptions)
+
+	return
+}
+
+func genEndpointCertToStore(store CertStore, generateKeyAlgorithm string, subject pkix.Name, dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string, ttl time.Duration, caID string, id string, options *CertOptions) (err error) {
+	var (
+		ca           *CA
+		storeOptions *CertOptions
+	)
+
+	storeOptions, err = newStoreCertOptions(store, id, options)
+	if nil != err {
+		return
+	}
+
+	ca, err = readCAFromStore(store, caID, caID)
+	if nil != err {
+		return
+	}
+
+	err = ca.genEndpointCert(context.Background(), generateKeyAlgorithm, subject, dnsNames, ipAddresses, emailAddresses, uris, ttl, id, id, storeOptions)
+
+	return
+}
+
+// newStoreCertOptions returns a copy of options (or the default CertOptions if
+// nil) directing the generated Certificate and private key to store under id.
+// The CertOptions only meaningful for files are rejected.
+//
+func newStoreCertOptions(store CertStore, id string, options *CertOptions) (storeOptions *CertOptions, err error) {
+	if nil == store {
+		err = fmt.Errorf("store must not be nil")
+		return
+	}
+	if "" == id {
+		err = fmt.Errorf("id must not be empty")
+		return
+	}
+
+	storeOptions = &CertOptions{}
+	if nil != options {
+		*storeOptions = *options
+	}
+
+	switch {
+	case "" != storeOptions.ExistingKeyFile:
+		err = fmt.Errorf("ExistingKeyFile may not be specified when generating into a CertStore")
+	case storeOptions.Lock:
+		err = fmt.Errorf("Lock may not be specified when generating into a CertStore")
+	case storeOptions.SkipIfValidFor > time.Duration(0):
+		err = fmt.Errorf("SkipIfValidFor may not be specified when generating into a CertStore")
+	}
+	if nil != err {
+		storeOptions = nil
+		return
+	}
+
+	storeOptions.certStore = store
+
+	return
+}
+
+// readCAFromStore is readCA() for the CA Certificate held by store under
+// caCertID and its private key held under caKeyID.
+//
+func readCAFromStore(store CertStore, caCertID string, caKeyID string) (ca *CA, err error) {
+	var (
+		caCertPEM        []byte
+		caKeyPEM         []byte
+		caTLSCertificate tls.Certificate
+	)
+
+	caCertPEM, err = store.ReadCert(caCertID)
+	if nil == err {
+		caKeyPEM, err = store.ReadKey(caKeyID)
+	}
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("%w: %v", ErrCANotFound, err)
+		}
+		return
+	}
+
+	caTLSCertificate, err = parseKeyPair(caCertPEM, caCertID, caKeyPEM, caKeyID)
+	if nil != err {
+		err = fmt.Errorf("CA \"%s\" in CertStore: %w", caCertID, err)
+		return
+	}
+
+	ca, err = newCA(caTLSCertificate, "CA private key \""+caKeyID+"\" in CertStore")
+
+	return
+}
+
+// writeCertAndKey is writeCertAndKeyFiles() unless options.certStore is set in
+// which case the Certificate and (if non-nil) private key are written to it
+// with certFile and keyFile taken as their ids. Just as for files, should
+// writing the Certificate fail, the private key is rolled back (see
+// rollbackStoreKey()) so that the new key is never left beside the old
+// Certificate.
+//
+func (options *CertOptions) writeCertAndKey(x509Certificate []byte, pkcs8PrivateKey []byte, certFile string, keyFile string, overwriteKey bool) (err error) {
+	var (
+		oldKeyPEM   []byte
+		rollbackErr error
+	)
+
+	if nil == options.certStore {
+		err = writeCertAndKeyFiles(x509Certificate, pkcs8PrivateKey, certFile, keyFile, overwriteKey)
+		return
+	}
+
+	if nil != pkcs8PrivateKey {
+		oldKeyPEM, err = options.certStore.ReadKey(keyFile)
+		if nil == err {
+			if !overwriteKey {
+				err = fmt.Errorf("refusing to overwrite existing key \"%s\" in CertStore: %w", keyFile, os.ErrExist)
+				return
+			}
+		} else if errors.Is(err, os.ErrNotExist) {
+			oldKeyPEM = nil
+		} else {
+			return
+		}
+
+		err = options.certStore.WriteKey(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8PrivateKey}))
+		if nil != err {
+			return
+		}
+	}
+
+	err = options.certStore.WriteCert(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: x509Certificate}))
+	if (nil != err) && (nil != pkcs8PrivateKey) {
+		rollbackErr = rollbackStoreKey(options.certStore, keyFile, oldKeyPEM)
+		if nil != rollbackErr {
+			err = fmt.Errorf("%w (and rolling back key \"%s\" in CertStore failed: %v)", err, keyFile, rollbackErr)
+		}
+	}
+
+	return
+}
+
+// rollbackStoreKey restores the private key of keyID in store to oldKeyPEM or,
+// if oldKeyPEM is nil (i.e. keyID had no private key), deletes it (which store
+// must implement KeyDeleter to permit).
+//
+func rollbackStoreKey(store CertStore, keyID string, oldKeyPEM []byte) (err error) {
+	var (
+		keyDeleter KeyDeleter
+		ok         bool
+	)
+
+	if nil != oldKeyPEM {
+		err = store.WriteKey(keyID, oldKeyPEM)
+		return
+	}
+
+	keyDeleter, ok = store.(KeyDeleter)
+	if !ok {
+		err = fmt.Errorf("CertStore does not implement KeyDeleter")
+		return
+	}
+
+	err = keyDeleter.DeleteKey(keyID)
+
+	return
+}
+
+func newFileCertStore(dir string) (fileCertStore *FileCertStore) {
+	fileCertStore = &FileCertStore{dir: dir}
+	return
+}
+
+// checkCertID ensures id names a file directly within FileCertStore.dir by
+// rejecting an empty id or one containing a path separator or "..".
+//
+func checkCertID(id string) (err error) {
+	if ("" == id) || strings.ContainsRune(id, '/') || strings.ContainsRune(id, os.PathSeparator) || strings.Contains(id, "..") {
+		err = fmt.Errorf("%w: \"%s\"", ErrInvalidCertID, id)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (fileCertStore *FileCertStore) certPath(id string) (path string, err error) {
+	err = checkCertID(id)
+	if nil != err {
+		return
+	}
+
+	path = filepath.Join(fileCertStore.dir, id+FileCertStoreCertSuffix)
+	return
+}
+
+func (fileCertStore *FileCertStore) keyPath(id string) (path string, err error) {
+	err = checkCertID(id)
+	if nil != err {
+		return
+	}
+
+	path = filepath.Join(fileCertStore.dir, id+FileCertStoreKeySuffix)
+	return
+}
+
+func (fileCertStore *FileCertStore) readCert(id string) (certPEM []byte, err error) {
+	var (
+		path string
+	)
+
+	path, err = fileCertStore.certPath(id)
+	if nil != err {
+		return
+	}
+
+	certPEM, err = ioutil.ReadFile(path)
+	return
+}
+
+func (fileCertStore *FileCertStore) listCertIDs() (ids []string, err error) {
+	var (
+		fileInfo  os.FileInfo
+		fileInfos []os.FileInfo
+	)
+
+	fileInfos, err = ioutil.ReadDir(fileCertStore.dir)
+	if nil != err {
+		return
+	}
+
+	ids = make([]string, 0, len(fileInfos))
+
+	for _, fileInfo = range fileInfos {
+		if fileInfo.Mode().IsRegular() && strings.HasSuffix(fileInfo.Name(), FileCertStoreCertSuffix) {
+			ids = append(ids, strings.TrimSuffix(fileInfo.Name(), FileCertStoreCertSuffix))
+		}
+	}
+
+	sort.Strings(ids)
+
+	return
+}
+
+func (fileCertStore *FileCertStore) readKey(id string) (keyPEM []byte, err error) {
+	var (
+		path string
+	)
+
+	path, err = fileCertStore.keyPath(id)
+	if nil != err {
+		return
+	}
+
+	keyPEM, err = ioutil.ReadFile(path)
+	return
+}
+
+func (fileCertStore *FileCertStore) writeCert(id string, certPEM []byte) (err error) {
+	var (
+		path string
+	)
+
+	path, err = fileCertStore.certPath(id)
+	if nil != err {
+		return
+	}
+
+	err = writeStoreFile(path, certPEM, GeneratedFilePerm)
+	return
+}
+
+func (fileCertStore *FileCertStore) deleteKey(id string) (err error) {
+	var (
+		path string
+	)
+
+	path, err = fileCertStore.keyPath(id)
+	if nil != err {
+		return
+	}
+
+	err = os.Remove(path)
+	return
+}
+
+func (fileCertStore *FileCertStore) writeKey(id string, keyPEM []byte) (err error) {
+	var (
+		path string
+	)
+
+	path, err = fileCertStore.keyPath(id)
+	if nil != err {
+		return
+	}
+
+	err = writeStoreFile(path, keyPEM, GeneratedKeyFilePerm)
+	return
+}
+
+// writeStoreFile atomically replaces path with data and mode perm.
+//
+func writeStoreFile(path string, data []byte, perm os.FileMode) (err error) {
+	var (
+		tmpFile string
+	)
+
+	tmpFile, err = writeTmpFile(path, data, perm)
+	if nil != err {
+		return
+	}
+
+	err = installTmpFile(tmpFile, path, true)
+	if nil != err {
+		_ = os.Remove(tmpFile)
+	}
+
+	return
+}
+
+func newInMemoryCertStore() (inMemoryCertStore *InMemoryCertStore) {
+	inMemoryCertStore = &InMemoryCertStore{
+		certs: make(map[string][]byte),
+		keys:  make(map[string][]byte),
+	}
+
+	return
+}
+
+func (inMemoryCertStore *InMemoryCertStore) readCert(id string) (certPEM []byte, err error) {
+	certPEM, err = inMemoryCertStore.read(inMemoryCertStore.certs, "Certificate", id)
+	return
+}
+
+func (inMemoryCertStore *InMemoryCertStore) listCertIDs() (ids []string, err error) {
+	var (
+		id string
+	)
+
+	inMemoryCertStore.Lock()
+
+	ids = make([]string, 0, len(inMemoryCertStore.certs))
+	for id = range inMemoryCertStore.certs {
+		ids = append(ids, id)
+	}
+
+	inMemoryCertStore.Unlock()
+
+	sort.Strings(ids)
+
+	return
+}
+
+func (inMemoryCertStore *InMemoryCertStore) readKey(id string) (keyPEM []byte, err error) {
+	keyPEM, err = inMemoryCertStore.read(inMemoryCertStore.keys, "private key", id)
+	return
+}
+
+func (inMemoryCertStore *InMemoryCertStore) writeCert(id string, certPEM []byte) (err error) {
+	inMemoryCertStore.write(inMemoryCertStore.certs, id, certPEM)
+	return
+}
+
+func (inMemoryCertStore *InMemoryCertStore) writeKey(id string, keyPEM []byte) (err error) {
+	inMemoryCertStore.write(inMemoryCertStore.keys, id, keyPEM)
+	return
+}
+
+func (inMemoryCertStore *InMemoryCertStore) deleteKey(id string) (err error) {
+	var (
+		ok bool
+	)
+
+	inMemoryCertStore.Lock()
+	defer inMemoryCertStore.Unlock()
+
+	_, ok = inMemoryCertStore.keys[id]
+	if !ok {
+		err = fmt.Errorf("private key \"%s\" not found in InMemoryCertStore: %w", id, os.ErrNotExist)
+		return
+	}
+
+	delete(inMemoryCertStore.keys, id)
+
+	err = nil
+	return
+}
+
+// read returns a copy of what entries holds for id or, if nothing, an error
+// wrapping os.ErrNotExist.
+//
+func (inMemoryCertStore *InMemoryCertStore) read(entries map[string][]byte, kind string, id string) (entry []byte, err error) {
+	var (
+		ok bool
+	)
+
+	inMemoryCertStore.Lock()
+	defer inMemoryCertStore.Unlock()
+
+	entry, ok = entries[id]
+	if !ok {
+		err = fmt.Errorf("%s \"%s\" not found in InMemoryCertStore: %w", kind, id, os.ErrNotExist)
+		return
+	}
+
+	entry = append([]byte(nil), entry...)
+
+	return
+}
+
+func (inMemoryCertStore *InMemoryCertStore) write(entries map[string][]byte, id string, entry []byte) {
+	inMemoryCertStore.Lock()
+	entries[id] = append([]byte(nil), entry...)
+	inMemoryCertStore.Unlock()
+}
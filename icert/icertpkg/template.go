@@ -0,0 +1,337 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/NVIDIA/proxyfs/conf"
+)
+
+type hostTemplateResolver struct {
+	cluster string
+	env     string
+}
+
+func newHostTemplateResolver(cluster string, env string) (resolver *hostTemplateResolver) {
+	resolver = &hostTemplateResolver{
+		cluster: cluster,
+		env:     env,
+	}
+
+	return
+}
+
+func (resolver *hostTemplateResolver) ResolveTemplatePlaceholder(placeholder string) (value string, err error) {
+	var (
+		addr      net.Addr
+		addrs     []net.Addr
+		canonical string
+		hostname  string
+		ipNet     *net.IPNet
+		ok        bool
+	)
+
+	switch placeholder {
+	case TemplatePlaceholderHostname:
+		value, err = os.Hostname()
+	case TemplatePlaceholderFQDN:
+		hostname, err = os.Hostname()
+		if nil != err {
+			return
+		}
+		canonical, err = net.LookupCNAME(hostname)
+		if nil != err {
+			return
+		}
+		value = strings.TrimSuffix(canonical, ".")
+	case TemplatePlaceholderIP4:
+		addrs, err = net.InterfaceAddrs()
+		if nil != err {
+			return
+		}
+		for _, addr = range addrs {
+			ipNet, ok = addr.(*net.IPNet)
+			if ok && !ipNet.IP.IsLoopback() && (nil != ipNet.IP.To4()) {
+				value = ipNet.IP.String()
+				return
+			}
+		}
+		err = fmt.Errorf("no non-loopback IPv4 address found for {{%s}}", placeholder)
+	case TemplatePlaceholderCluster:
+		value = resolver.cluster
+	case TemplatePlaceholderEnv:
+		value = resolver.env
+	default:
+		err = fmt.Errorf("unknown placeholder {{%s}}", placeholder)
+	}
+
+	if (nil == err) && ("" == value) {
+		err = fmt.Errorf("placeholder {{%s}} resolved to an empty value", placeholder)
+	}
+
+	return
+}
+
+func loadIssuanceTemplate(confMap conf.ConfMap, templateName string) (issuanceTemplate *IssuanceTemplate, err error) {
+	var (
+		ok              bool
+		optionName      string
+		optionValue     conf.ConfMapOption
+		section         conf.ConfMapSection
+		sectionName     string
+		stringSliceDest *[]string
+	)
+
+	sectionName = IssuanceTemplateSectionPrefix + templateName
+
+	section, ok = confMap[sectionName]
+	if !ok {
+		err = fmt.Errorf("Section '[%v]' is missing", sectionName)
+		return
+	}
+
+	issuanceTemplate = &IssuanceTemplate{}
+
+	for optionName, optionValue = range section {
+		stringSliceDest = nil
+
+		switch optionName {
+		case "Organization":
+			stringSliceDest = &issuanceTemplate.Organization
+		case "Country":
+			stringSliceDest = &issuanceTemplate.Country
+		case "Province":
+			stringSliceDest = &issuanceTemplate.Province
+		case "Locality":
+			stringSliceDest = &issuanceTemplate.Locality
+		case "StreetAddress":
+			stringSliceDest = &issuanceTemplate.StreetAddress
+		case "PostalCode":
+			stringSliceDest = &issuanceTemplate.PostalCode
+		case "CommonName":
+			issuanceTemplate.CommonName, err = confMap.FetchOptionValueString(sectionName, optionName)
+			if nil != err {
+				issuanceTemplate = nil
+				return
+			}
+		case "DNSNames":
+			stringSliceDest = &issuanceTemplate.DNSNames
+		case "IPAddresses":
+			stringSliceDest = &issuanceTemplate.IPAddresses
+		case "EmailAddresses":
+			stringSliceDest = &issuanceTemplate.EmailAddresses
+		case "URIs":
+			stringSliceDest = &issuanceTemplate.URIs
+		default:
+			issuanceTemplate = nil
+			err = fmt.Errorf("Option '[%v]%v' is not supported", sectionName, optionName)
+			return
+		}
+
+		if nil != stringSliceDest {
+			*stringSliceDest = append([]string{}, optionValue...)
+		}
+	}
+
+	err = nil
+	return
+}
+
+// templateExpander expands placeholders consulting resolver at most once for
+// each placeholder.
+//
+type templateExpander struct {
+	resolver TemplateResolver
+	resolved map[string]string
+}
+
+func expandIssuanceTemplate(issuanceTemplate *IssuanceTemplate, resolver TemplateResolver) (expandedIssuance *ExpandedIssuance, err error) {
+	var (
+		expandedIPAddresses []string
+		expander            *templateExpander
+		ipAddress           net.IP
+	)
+
+	expander = &templateExpander{
+		resolver: resolver,
+		resolved: make(map[string]string),
+	}
+
+	expandedIssuance = &ExpandedIssuance{}
+
+	expandedIssuance.Subject.Organization, err = expander.expandSlice(issuanceTemplate.Organization)
+	if nil == err {
+		expandedIssuance.Subject.Country, err = expander.expandSlice(issuanceTemplate.Country)
+	}
+	if nil == err {
+		expandedIssuance.Subject.Province, err = expander.expandSlice(issuanceTemplate.Province)
+	}
+	if nil == err {
+		expandedIssuance.Subject.Locality, err = expander.expandSlice(issuanceTemplate.Locality)
+	}
+	if nil == err {
+		expandedIssuance.Subject.StreetAddress, err = expander.expandSlice(issuanceTemplate.StreetAddress)
+	}
+	if nil == err {
+		expandedIssuance.Subject.PostalCode, err = expander.expandSlice(issuanceTemplate.PostalCode)
+	}
+	if nil == err {
+		expandedIssuance.Subject.CommonName, err = expander.expand(issuanceTemplate.CommonName)
+	}
+	if nil == err {
+		expandedIssuance.DNSNames, err = expander.expandSlice(issuanceTemplate.DNSNames)
+	}
+	if nil == err {
+		expandedIPAddresses, err = expander.expandSlice(issuanceTemplate.IPAddresses)
+	}
+	if nil == err {
+		expandedIssuance.EmailAddresses, err = expander.expandSlice(issuanceTemplate.EmailAddresses)
+	}
+	if nil == err {
+		expandedIssuance.URIs, err = expander.expandSlice(issuanceTemplate.URIs)
+	}
+	if nil != err {
+		expandedIssuance = nil
+		return
+	}
+
+	expandedIssuance.IPAddresses = make([]net.IP, 0, len(expandedIPAddresses))
+
+	for _, expandedIPAddress := range expandedIPAddresses {
+		ipAddress = net.ParseIP(expandedIPAddress)
+		if nil == ipAddress {
+			expandedIssuance = nil
+			err = fmt.Errorf("IPAddresses value \"%s\" is not an IP Address", expandedIPAddress)
+			return
+		}
+		expandedIssuance.IPAddresses = append(expandedIssuance.IPAddresses, ipAddress)
+	}
+
+	expandedIssuance.Resolved = expander.resolved
+
+	err = nil
+	return
+}
+
+func (expander *templateExpander) expandSlice(values []string) (expandedValues []string, err error) {
+	var (
+		expandedValue string
+	)
+
+	if nil == values {
+		expandedValues = nil
+		err = nil
+		return
+	}
+
+	expandedValues = make([]string, 0, len(values))
+
+	for _, value := range values {
+		expandedValue, err = expander.expand(value)
+		if nil != err {
+			expandedValues = nil
+			return
+		}
+		expandedValues = append(expandedValues, expandedValue)
+	}
+
+	err = nil
+	return
+}
+
+func (expander *templateExpander) expand(value string) (expandedValue string, err error) {
+	var (
+		closeIndex    int
+		ok            bool
+		openIndex     int
+		placeholder   string
+		resolvedValue string
+	)
+
+	for {
+		openIndex = strings.Index(value, "{{")
+		if openIndex < 0 {
+			break
+		}
+
+		closeIndex = strings.Index(value[openIndex:], "}}")
+		if closeIndex < 0 {
+			err = fmt.Errorf("unterminated placeholder in \"%s\"", value)
+			return
+		}
+		closeIndex += openIndex
+
+		placeholder = value[openIndex+2 : closeIndex]
+
+		switch placeholder {
+		case TemplatePlaceholderHostname, TemplatePlaceholderFQDN, TemplatePlaceholderIP4, TemplatePlaceholderCluster, TemplatePlaceholderEnv:
+		default:
+			err = fmt.Errorf("unknown placeholder {{%s}} in \"%s\"", placeholder, value)
+			return
+		}
+
+		resolvedValue, ok = expander.resolved[placeholder]
+		if !ok {
+			resolvedValue, err = expander.resolver.ResolveTemplatePlaceholder(placeholder)
+			if nil != err {
+				err = fmt.Errorf("unable to resolve placeholder {{%s}}: %w", placeholder, err)
+				return
+			}
+			expander.resolved[placeholder] = resolvedValue
+		}
+
+		expandedValue += value[:openIndex] + resolvedValue
+		value = value[closeIndex+2:]
+	}
+
+	if strings.Contains(value, "}}") {
+		err = fmt.Errorf("unmatched \"}}\" in \"%s\"", value)
+		return
+	}
+
+	expandedValue += value
+
+	err = nil
+	return
+}
+
+func (expandedIssuance *ExpandedIssuance) appendSANs(dnsNames []string, ipAddresses []net.IP, emailAddresses []string, uris []string) {
+	expandedIssuance.DNSNames = appendUniqueStrings(expandedIssuance.DNSNames, dnsNames)
+	expandedIssuance.EmailAddresses = appendUniqueStrings(expandedIssuance.EmailAddresses, emailAddresses)
+	expandedIssuance.URIs = appendUniqueStrings(expandedIssuance.URIs, uris)
+
+ipAddressLoop:
+	for _, ipAddress := range ipAddresses {
+		for _, existingIPAddress := range expandedIssuance.IPAddresses {
+			if existingIPAddress.Equal(ipAddress) {
+				continue ipAddressLoop
+			}
+		}
+		expandedIssuance.IPAddresses = append(expandedIssuance.IPAddresses, ipAddress)
+	}
+}
+
+func appendUniqueStrings(existingValues []string, values []string) (combinedValues []string) {
+	var (
+		seen map[string]struct{}
+		ok   bool
+	)
+
+	seen = make(map[string]struct{}, len(existingValues)+len(values))
+	combinedValues = make([]string, 0, len(existingValues)+len(values))
+
+	for _, value := range append(append([]string{}, existingValues...), values...) {
+		_, ok = seen[value]
+		if !ok {
+			seen[value] = struct{}{}
+			combinedValues = append(combinedValues, value)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"strings"
+	"time"
+)
+
+// weakCipherSuiteNameFragments identify (by name) the cipher suites never
+// returned by RecommendedCipherSuites().
+//
+var weakCipherSuiteNameFragments = []string{
+	"_RC4_",
+	"_3DES_",
+	"_EXPORT",
+	"_NULL_",
+}
+
+func hardenedTLSConfig(base *tls.Config, minVersion uint16, allowedCipherSuites []uint16) (hardenedConfig *tls.Config) {
+	var (
+		cipherSuite   uint16
+		isRecommended bool
+		recommended   map[uint16]struct{}
+	)
+
+	if nil == base {
+		hardenedConfig = &tls.Config{}
+	} else {
+		hardenedConfig = base.Clone()
+	}
+
+	if minVersion < HardenedTLSMinVersion {
+		minVersion = HardenedTLSMinVersion
+	}
+
+	hardenedConfig.MinVersion = minVersion
+	if (0 != hardenedConfig.MaxVersion) && (hardenedConfig.MaxVersion < minVersion) {
+		hardenedConfig.MaxVersion = 0
+	}
+
+	if nil == allowedCipherSuites {
+		hardenedConfig.CipherSuites = recommendedCipherSuites()
+		return
+	}
+
+	recommended = make(map[uint16]struct{})
+
+	for _, cipherSuite = range recommendedCipherSuites() {
+		recommended[cipherSuite] = struct{}{}
+	}
+
+	hardenedConfig.CipherSuites = make([]uint16, 0, len(allowedCipherSuites))
+
+	for _, cipherSuite = range allowedCipherSuites {
+		_, isRecommended = recommended[cipherSuite]
+		if isRecommended {
+			hardenedConfig.CipherSuites = append(hardenedConfig.CipherSuites, cipherSuite)
+		}
+	}
+
+	return
+}
+
+func recommendedCipherSuites() (cipherSuites []uint16) {
+	var (
+		cipherSuite *tls.CipherSuite
+		fragment    string
+		weak        bool
+	)
+
+	cipherSuites = make([]uint16, 0, len(tls.CipherSuites()))
+
+	for _, cipherSuite = range tls.CipherSuites() {
+		weak = cipherSuite.Insecure
+		for _, fragment = range weakCipherSuiteNameFragments {
+			if strings.Contains(cipherSuite.Name, fragment) {
+				weak = true
+				break
+			}
+		}
+		if !weak {
+			cipherSuites = append(cipherSuites, cipherSuite.ID)
+		}
+	}
+
+	return
+}
+
+func newTLSListener(network string, addr string, certPemFilePath string, keyPemFilePath string) (netListener net.Listener, err error) {
+	var (
+		tlsCertificate tls.Certificate
+	)
+
+	if "" == keyPemFilePath {
+		keyPemFilePath = certPemFilePath
+	}
+
+	tlsCertificate, err = loadKeyPair(certPemFilePath, keyPemFilePath)
+	if nil != err {
+		return
+	}
+
+	netListener, err = tls.Listen(network, addr, hardenedTLSConfig(&tls.Config{Certificates: []tls.Certificate{tlsCertificate}}, 0, nil))
+
+	return
+}
+
+func newTLSDialConfig(caCertPemFilePath string) (tlsConfig *tls.Config, err error) {
+	var (
+		caX509Certificate  *x509.Certificate
+		caX509Certificates []*x509.Certificate
+		rootCAs            *x509.CertPool
+	)
+
+	caX509Certificates, err = loadCertChain(caCertPemFilePath)
+	if nil != err {
+		return
+	}
+
+	rootCAs = x509.NewCertPool()
+	for _, caX509Certificate = range caX509Certificates {
+		rootCAs.AddCert(caX509Certificate)
+	}
+
+	tlsConfig = hardenedTLSConfig(&tls.Config{RootCAs: rootCAs}, 0, nil)
+
+	return
+}
+
+func dial(network string, addr string, caCertPemFilePath string, timeout time.Duration) (tlsConn *tls.Conn, err error) {
+	var (
+		tlsConfig *tls.Config
+	)
+
+	tlsConfig, err = newTLSDialConfig(caCertPemFilePath)
+	if nil != err {
+		return
+	}
+
+	tlsConn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, network, addr, tlsConfig)
+
+	return
+}
@@ -0,0 +1,386 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	trustDebianAnchorDir = "/usr/local/share/ca-certificates"
+	trustRHELAnchorDir   = "/etc/pki/ca-trust/source/anchors"
+	trustDarwinKeychain  = "/Library/Keychains/System.keychain"
+	trustWindowsStore    = "Root"
+
+	trustAnchorFilePrefix = "icert-"
+	trustAnchorFileIDLen  = 16 // hexadecimal digits of the SHA-256 fingerprint
+)
+
+// trustRecordStruct is the JSON content of a caCertPath+TrustRecordSuffix file
+// recording precisely what InstallCAToSystemTrust() installed.
+//
+type trustRecordStruct struct {
+	Platform    string `json:"platform"`
+	Fingerprint string `json:"fingerprint"`          // SHA-256 of the DER encoding as lowercase hexadecimal
+	Thumbprint  string `json:"thumbprint"`           // SHA-1 of the DER encoding as lowercase hexadecimal
+	AnchorFile  string `json:"anchorFile,omitempty"` // Copy of the CA Certificate placed in a trust anchor directory
+}
+
+// trustPrivilegeIndicators are (lowercased) fragments of the output of a failed
+// trust store command indicating that it lacked the necessary privileges.
+//
+var trustPrivilegeIndicators = []string{
+	"permission denied",
+	"not permitted",
+	"must be root",
+	"access is denied",
+	"authorization",
+}
+
+func installCAToSystemTrust(caCertPath string, options *TrustOptions) (err error) {
+	var (
+		oldTrustRecord *trustRecordStruct
+		trustRecord    *trustRecordStruct
+	)
+
+	if nil == options {
+		options = &TrustOptions{}
+	}
+
+	if !options.Confirm {
+		err = fmt.Errorf("%w: installing \"%s\" into the system trust store", ErrTrustNotConfirmed, caCertPath)
+		return
+	}
+
+	trustRecord, err = options.newTrustRecord(caCertPath)
+	if nil != err {
+		return
+	}
+
+	oldTrustRecord, err = loadTrustRecord(caCertPath)
+	if nil != err {
+		return
+	}
+
+	if nil != oldTrustRecord {
+		if *oldTrustRecord == *trustRecord {
+			return // Already installed
+		}
+
+		// caCertPath has been replaced since it was installed... so remove what was installed
+
+		err = options.runTrustCommands(oldTrustRecord.removeCommands())
+		if nil != err {
+			return
+		}
+		err = os.Remove(caCertPath + TrustRecordSuffix)
+		if nil != err {
+			return
+		}
+	}
+
+	err = options.runTrustCommands(trustRecord.installCommands(caCertPath))
+	if nil != err {
+		// Best effort to leave nothing partially installed behind
+		_ = options.runTrustCommands(trustRecord.removeCommands())
+		return
+	}
+
+	err = saveTrustRecord(caCertPath, trustRecord)
+
+	return
+}
+
+func removeCAFromSystemTrust(caCertPath string, options *TrustOptions) (err error) {
+	var (
+		trustRecord *trustRecordStruct
+	)
+
+	if nil == options {
+		options = &TrustOptions{}
+	}
+
+	if !options.Confirm {
+		err = fmt.Errorf("%w: removing \"%s\" from the system trust store", ErrTrustNotConfirmed, caCertPath)
+		return
+	}
+
+	trustRecord, err = loadTrustRecord(caCertPath)
+	if (nil != err) || (nil == trustRecord) {
+		return // Either failed or nothing installed
+	}
+
+	err = options.runTrustCommands(trustRecord.removeCommands())
+	if nil != err {
+		return
+	}
+
+	err = os.Remove(caCertPath + TrustRecordSuffix)
+
+	return
+}
+
+// newTrustRecord returns the trustRecordStruct describing the installation of
+// the (first) CA Certificate in caCertPath on the selected platform.
+//
+func (options *TrustOptions) newTrustRecord(caCertPath string) (trustRecord *trustRecordStruct, err error) {
+	var (
+		caX509Certificate *x509.Certificate
+		fingerprint       [sha256.Size]byte
+		thumbprint        [sha1.Size]byte
+	)
+
+	caX509Certificate, err = loadFirstCert(caCertPath)
+	if nil != err {
+		return
+	}
+
+	if !caX509Certificate.IsCA {
+		err = fmt.Errorf("%w: \"%s\" in \"%s\"", ErrNotCACert, caX509Certificate.Subject, caCertPath)
+		return
+	}
+
+	fingerprint = sha256.Sum256(caX509Certificate.Raw)
+	thumbprint = sha1.Sum(caX509Certificate.Raw)
+
+	trustRecord = &trustRecordStruct{
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		Thumbprint:  hex.EncodeToString(thumbprint[:]),
+	}
+
+	trustRecord.Platform, err = options.platform()
+	if nil != err {
+		trustRecord = nil
+		return
+	}
+
+	switch trustRecord.Platform {
+	case TrustPlatformDebian:
+		trustRecord.AnchorFile = filepath.Join(trustDebianAnchorDir, trustAnchorFilePrefix+trustRecord.Fingerprint[:trustAnchorFileIDLen]+".crt")
+	case TrustPlatformRHEL:
+		trustRecord.AnchorFile = filepath.Join(trustRHELAnchorDir, trustAnchorFilePrefix+trustRecord.Fingerprint[:trustAnchorFileIDLen]+".pem")
+	}
+
+	return
+}
+
+// platform returns options.Platform if specified or else that detected for the
+// running system.
+//
+func (options *TrustOptions) platform() (platform string, err error) {
+	var (
+		lookPath func(file string) (string, error)
+	)
+
+	switch options.Platform {
+	case "":
+		// Detect below
+	case TrustPlatformDebian, TrustPlatformRHEL, TrustPlatformDarwin, TrustPlatformWindows:
+		platform = options.Platform
+		return
+	default:
+		err = fmt.Errorf("Platform \"%s\" not supported... must be one of \"%s\", \"%s\", \"%s\", or \"%s\"", options.Platform, TrustPlatformDebian, TrustPlatformRHEL, TrustPlatformDarwin, TrustPlatformWindows)
+		return
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		platform = TrustPlatformDarwin
+	case "windows":
+		platform = TrustPlatformWindows
+	case "linux":
+		lookPath = options.LookPath
+		if nil == lookPath {
+			lookPath = exec.LookPath
+		}
+		if _, err = lookPath("update-ca-certificates"); nil == err {
+			platform = TrustPlatformDebian
+		} else if _, err = lookPath("update-ca-trust"); nil == err {
+			platform = TrustPlatformRHEL
+		} else {
+			err = fmt.Errorf("neither update-ca-certificates nor update-ca-trust found... unable to detect system trust store")
+		}
+	default:
+		err = fmt.Errorf("system trust store of GOOS \"%s\" not supported", runtime.GOOS)
+	}
+
+	return
+}
+
+func (trustRecord *trustRecordStruct) installCommands(caCertPath string) (commands [][]string) {
+	switch trustRecord.Platform {
+	case TrustPlatformDebian:
+		commands = [][]string{
+			{"install", "-m", "0644", caCertPath, trustRecord.AnchorFile},
+			{"update-ca-certificates"},
+		}
+	case TrustPlatformRHEL:
+		commands = [][]string{
+			{"install", "-m", "0644", caCertPath, trustRecord.AnchorFile},
+			{"update-ca-trust", "extract"},
+		}
+	case TrustPlatformDarwin:
+		commands = [][]string{
+			{"security", "add-trusted-cert", "-d", "-r", "trustRoot", "-k", trustDarwinKeychain, caCertPath},
+		}
+	case TrustPlatformWindows:
+		commands = [][]string{
+			{"certutil", "-addstore", "-f", trustWindowsStore, caCertPath},
+		}
+	}
+
+	return
+}
+
+func (trustRecord *trustRecordStruct) removeCommands() (commands [][]string) {
+	switch trustRecord.Platform {
+	case TrustPlatformDebian:
+		commands = [][]string{
+			{"rm", "-f", trustRecord.AnchorFile},
+			{"update-ca-certificates", "--fresh"},
+		}
+	case TrustPlatformRHEL:
+		commands = [][]string{
+			{"rm", "-f", trustRecord.AnchorFile},
+			{"update-ca-trust", "extract"},
+		}
+	case TrustPlatformDarwin:
+		commands = [][]string{
+			{"security", "delete-certificate", "-Z", strings.ToUpper(trustRecord.Thumbprint), trustDarwinKeychain},
+		}
+	case TrustPlatformWindows:
+		commands = [][]string{
+			{"certutil", "-delstore", trustWindowsStore, trustRecord.Thumbprint},
+		}
+	}
+
+	return
+}
+
+// runTrustCommands runs each of commands in turn via options.Runner (or, by
+// default, os/exec) stopping at the first to fail.
+//
+func (options *TrustOptions) runTrustCommands(commands [][]string) (err error) {
+	var (
+		command []string
+		output  []byte
+		runner  func(name string, args ...string) (output []byte, err error)
+	)
+
+	runner = options.Runner
+	if nil == runner {
+		runner = runTrustCommand
+	}
+
+	for _, command = range commands {
+		output, err = runner(command[0], command[1:]...)
+		if nil != err {
+			if trustOutputIndicatesPrivilege(output) {
+				err = fmt.Errorf("%w: \"%s\" failed (%v): %s... %s", ErrInsufficientPrivileges, strings.Join(command, " "), err, strings.TrimSpace(string(output)), trustPrivilegeHint(command))
+			} else {
+				err = fmt.Errorf("\"%s\" failed (%v): %s", strings.Join(command, " "), err, strings.TrimSpace(string(output)))
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func runTrustCommand(name string, args ...string) (output []byte, err error) {
+	output, err = exec.Command(name, args...).CombinedOutput()
+	return
+}
+
+func trustOutputIndicatesPrivilege(output []byte) bool {
+	var (
+		lowercaseOutput string
+		indicator       string
+	)
+
+	lowercaseOutput = strings.ToLower(string(output))
+
+	for _, indicator = range trustPrivilegeIndicators {
+		if strings.Contains(lowercaseOutput, indicator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func trustPrivilegeHint(command []string) string {
+	if "certutil" == command[0] {
+		return "re-run from an elevated (Run as administrator) prompt"
+	}
+
+	return "re-run as root (e.g. via sudo)"
+}
+
+// loadTrustRecord returns the trustRecordStruct recorded for caCertPath or nil
+// if none has been recorded.
+//
+func loadTrustRecord(caCertPath string) (trustRecord *trustRecordStruct, err error) {
+	var (
+		trustRecordJSON []byte
+	)
+
+	trustRecordJSON, err = ioutil.ReadFile(caCertPath + TrustRecordSuffix)
+	if nil != err {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+
+	trustRecord = &trustRecordStruct{}
+
+	err = json.Unmarshal(trustRecordJSON, trustRecord)
+	if nil != err {
+		trustRecord = nil
+		err = fmt.Errorf("unable to parse trust record \"%s\": %v", caCertPath+TrustRecordSuffix, err)
+		return
+	}
+
+	return
+}
+
+func saveTrustRecord(caCertPath string, trustRecord *trustRecordStruct) (err error) {
+	var (
+		trustRecordJSON    []byte
+		trustRecordPath    string
+		trustRecordTmpFile string
+	)
+
+	trustRecordJSON, err = json.MarshalIndent(trustRecord, "", "  ")
+	if nil != err {
+		return
+	}
+
+	trustRecordPath = caCertPath + TrustRecordSuffix
+
+	trustRecordTmpFile, err = writeTmpFile(trustRecordPath, append(trustRecordJSON, '\n'), GeneratedFilePerm)
+	if nil != err {
+		return
+	}
+
+	err = installTmpFile(trustRecordTmpFile, trustRecordPath, true)
+	if nil != err {
+		_ = os.Remove(trustRecordTmpFile)
+	}
+
+	return
+}
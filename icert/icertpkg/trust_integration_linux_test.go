@@ -0,0 +1,94 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build trustintegration
+// +build trustintegration
+
+package icertpkg
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSystemTrustIntegration actually modifies the system trust store and so
+// is only built given `-tags trustintegration` and only run as root.
+//
+func TestSystemTrustIntegration(t *testing.T) {
+	var (
+		bundleFile            string
+		caCombinedPemFilePath string
+		err                   error
+		trustOptions          *TrustOptions
+		trustPlatform         string
+		tempDir               string
+	)
+
+	if 0 != os.Geteuid() {
+		t.Skip("modifying the system trust store requires running as root")
+	}
+
+	trustOptions = &TrustOptions{Confirm: true}
+
+	trustPlatform, err = trustOptions.platform()
+	if nil != err {
+		t.Skipf("no supported system trust store: %v", err)
+	}
+
+	switch trustPlatform {
+	case TrustPlatformDebian:
+		bundleFile = "/etc/ssl/certs/ca-certificates.crt"
+	case TrustPlatformRHEL:
+		bundleFile = "/etc/pki/tls/certs/ca-bundle.crt"
+	}
+
+	tempDir = testMakeTempDir(t)
+	defer testRemoveTempDir(t, tempDir)
+
+	caCombinedPemFilePath = filepath.Join(tempDir, testCACombinedPEMFileName)
+
+	err = GenCACert(GenerateKeyAlgorithmEd25519, pkix.Name{Organization: []string{testOrganizationCA}}, testCertificateTTL, caCombinedPemFilePath, caCombinedPemFilePath)
+	if nil != err {
+		t.Fatalf("GenCACert() failed: %v", err)
+	}
+
+	err = InstallCAToSystemTrust(caCombinedPemFilePath, trustOptions)
+	if nil != err {
+		t.Fatalf("InstallCAToSystemTrust() failed: %v", err)
+	}
+
+	if !testBundleContainsCert(t, bundleFile, caCombinedPemFilePath) {
+		_ = RemoveCAFromSystemTrust(caCombinedPemFilePath, trustOptions)
+		t.Fatalf("\"%s\" lacks the installed CA Certificate", bundleFile)
+	}
+
+	err = RemoveCAFromSystemTrust(caCombinedPemFilePath, trustOptions)
+	if nil != err {
+		t.Fatalf("RemoveCAFromSystemTrust() failed: %v", err)
+	}
+
+	if testBundleContainsCert(t, bundleFile, caCombinedPemFilePath) {
+		t.Fatalf("\"%s\" still contains the removed CA Certificate", bundleFile)
+	}
+}
+
+func testBundleContainsCert(t *testing.T, bundleFile string, certPemFilePath string) bool {
+	var (
+		bundlePEM []byte
+		err       error
+	)
+
+	bundlePEM, err = ioutil.ReadFile(bundleFile)
+	if nil != err {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+
+	// Bundles re-wrap each Certificate's base64 so compare with whitespace removed
+
+	return bytes.Contains(bytes.Join(bytes.Fields(bundlePEM), nil), []byte(base64.StdEncoding.EncodeToString(testLoadCert(t, certPemFilePath).Raw)))
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func verifyEndpointCert(certPath string, caCertPath string, dnsNameOrIP string, at time.Time) (err error) {
+	var (
+		caX509Certificate       *x509.Certificate
+		caX509Certificates      []*x509.Certificate
+		certificateInvalidError x509.CertificateInvalidError
+		hostnameError           x509.HostnameError
+		intermediates           *x509.CertPool
+		roots                   *x509.CertPool
+		unknownAuthorityError   x509.UnknownAuthorityError
+		x509Certificate         *x509.Certificate
+		x509Certificates        []*x509.Certificate
+	)
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	x509Certificates, err = loadCertChain(certPath)
+	if nil != err {
+		return
+	}
+
+	x509Certificates = orderCertChain(x509Certificates)
+
+	caX509Certificates, err = loadCertChain(caCertPath)
+	if nil != err {
+		return
+	}
+
+	roots = x509.NewCertPool()
+	for _, caX509Certificate = range caX509Certificates {
+		roots.AddCert(caX509Certificate)
+	}
+
+	intermediates = x509.NewCertPool()
+	for _, x509Certificate = range x509Certificates[1:] {
+		intermediates.AddCert(x509Certificate)
+	}
+
+	x509Certificate = x509Certificates[0]
+
+	_, err = x509Certificate.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if nil != err {
+		switch {
+		case errors.As(err, &certificateInvalidError) && (x509.Expired == certificateInvalidError.Reason):
+			if at.Before(certificateInvalidError.Cert.NotBefore) {
+				err = fmt.Errorf("%w: NotBefore (%v) of \"%s\" follows %v", ErrCertNotYetValid, certificateInvalidError.Cert.NotBefore, certificateInvalidError.Cert.Subject, at)
+			} else {
+				err = fmt.Errorf("%w: NotAfter (%v) of \"%s\" precedes %v", ErrCertExpired, certificateInvalidError.Cert.NotAfter, certificateInvalidError.Cert.Subject, at)
+			}
+		case errors.As(err, &certificateInvalidError) && (x509.CANotAuthorizedForThisName == certificateInvalidError.Reason):
+			err = fmt.Errorf("%w: %v", ErrNameNotPermitted, certificateInvalidError)
+		case errors.As(err, &certificateInvalidError) && (x509.TooManyIntermediates == certificateInvalidError.Reason):
+			err = fmt.Errorf("%w: %v", ErrPathLenExceeded, certificateInvalidError)
+		case errors.As(err, &unknownAuthorityError):
+			err = fmt.Errorf("%w: %v", ErrUnknownAuthority, unknownAuthorityError)
+		}
+		return
+	}
+
+	err = x509Certificate.VerifyHostname(dnsNameOrIP)
+	if nil != err {
+		if errors.As(err, &hostnameError) {
+			err = fmt.Errorf("%w: %v", ErrNameMismatch, hostnameError)
+		}
+		return
+	}
+
+	return
+}
+
+func verifyCertWasIssuedByCA(leafCertPEMPath string, caCertPEMPath string) (err error) {
+	var (
+		caX509Certificate   *x509.Certificate
+		leafX509Certificate *x509.Certificate
+	)
+
+	leafX509Certificate, err = loadFirstCert(leafCertPEMPath)
+	if nil != err {
+		return
+	}
+
+	caX509Certificate, err = loadFirstCert(caCertPEMPath)
+	if nil != err {
+		return
+	}
+
+	if !caX509Certificate.IsCA {
+		err = fmt.Errorf("%w: \"%s\" in \"%s\"", ErrNotCACert, caX509Certificate.Subject, caCertPEMPath)
+		return
+	}
+
+	err = leafX509Certificate.CheckSignatureFrom(caX509Certificate)
+	if nil != err {
+		err = fmt.Errorf("%w: \"%s\" in \"%s\" not issued by \"%s\" in \"%s\": %v", ErrSignatureMismatch, leafX509Certificate.Subject, leafCertPEMPath, caX509Certificate.Subject, caCertPEMPath, err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package icertpkg
+
+import (
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchAndReloadSettleInterval is how long to wait following a change for any
+// further changes (e.g. the cert following the key) before reloading.
+//
+const watchAndReloadSettleInterval = 50 * time.Millisecond
+
+type certWatcherStruct struct {
+	certPEMPath    string
+	keyPEMPath     string
+	onChange       func(cert tls.Certificate, err error)
+	watcher        *fsnotify.Watcher
+	tlsCertificate tls.Certificate
+	stopOnce       sync.Once
+	stopChan       chan struct{}
+	stopWG         sync.WaitGroup
+}
+
+func watchAndReload(certPEMPath string, keyPEMPath string, onChange func(cert tls.Certificate, err error)) (stop func(), err error) {
+	var (
+		certWatcher *certWatcherStruct
+	)
+
+	certWatcher, err = newCertWatcher(certPEMPath, keyPEMPath, onChange)
+	if nil != err {
+		return
+	}
+
+	stop = certWatcher.stop
+
+	return
+}
+
+func newCertWatcher(certPEMPath string, keyPEMPath string, onChange func(cert tls.Certificate, err error)) (certWatcher *certWatcherStruct, err error) {
+	var (
+		dir  string
+		dirs map[string]struct{}
+	)
+
+	if nil == onChange {
+		err = fmt.Errorf("onChange must not be nil")
+		return
+	}
+
+	certWatcher = &certWatcherStruct{
+		certPEMPath: filepath.Clean(certPEMPath),
+		keyPEMPath:  filepath.Clean(keyPEMPath),
+		onChange:    onChange,
+		stopChan:    make(chan struct{}),
+	}
+
+	certWatcher.tlsCertificate, err = loadKeyPair(certWatcher.certPEMPath, certWatcher.keyPEMPath)
+	if nil != err {
+		return
+	}
+
+	certWatcher.watcher, err = fsnotify.NewWatcher()
+	if nil != err {
+		return
+	}
+
+	// Watching the containing directories (rather than the files themselves)
+	// survives each file being replaced via rename
+
+	dirs = map[string]struct{}{
+		filepath.Dir(certWatcher.certPEMPath): {},
+		filepath.Dir(certWatcher.keyPEMPath):  {},
+	}
+
+	for dir = range dirs {
+		err = certWatcher.watcher.Add(dir)
+		if nil != err {
+			_ = certWatcher.watcher.Close()
+			return
+		}
+	}
+
+	certWatcher.stopWG.Add(1)
+	go certWatcher.watch()
+
+	err = nil
+	return
+}
+
+func (certWatcher *certWatcherStruct) watch() {
+	var (
+		err         error
+		event       fsnotify.Event
+		ok          bool
+		settleTimer *time.Timer
+		settleChan  <-chan time.Time
+	)
+
+	defer certWatcher.stopWG.Done()
+
+	for {
+		select {
+		case <-certWatcher.stopChan:
+			if nil != settleTimer {
+				_ = settleTimer.Stop()
+			}
+			return
+		case event, ok = <-certWatcher.watcher.Events:
+			if !ok {
+				return
+			}
+			if (0 == (event.Op & (fsnotify.Write | fsnotify.Create))) ||
+				((filepath.Clean(event.Name) != certWatcher.certPEMPath) && (filepath.Clean(event.Name) != certWatcher.keyPEMPath)) {
+				continue
+			}
+			if nil == settleTimer {
+				settleTimer = time.NewTimer(watchAndReloadSettleInterval)
+			} else {
+				if !settleTimer.Stop() {
+					select {
+					case <-settleTimer.C:
+					default:
+					}
+				}
+				settleTimer.Reset(watchAndReloadSettleInterval)
+			}
+			settleChan = settleTimer.C
+		case err, ok = <-certWatcher.watcher.Errors:
+			if !ok {
+				return
+			}
+			// A change may have gone unreported (e.g. if the event queue overflowed)
+			certWatcher.onChange(certWatcher.tlsCertificate, fmt.Errorf("watching \"%s\" and \"%s\" failed (a change may have been missed): %w", certWatcher.certPEMPath, certWatcher.keyPEMPath, err))
+		case <-settleChan:
+			settleChan = nil
+			certWatcher.reload()
+		}
+	}
+}
+
+func (certWatcher *certWatcherStruct) reload() {
+	var (
+		err            error
+		tlsCertificate tls.Certificate
+	)
+
+	tlsCertificate, err = loadKeyPair(certWatcher.certPEMPath, certWatcher.keyPEMPath)
+	if nil == err {
+		certWatcher.tlsCertificate = tlsCertificate
+	}
+
+	certWatcher.onChange(certWatcher.tlsCertificate, err)
+}
+
+func (certWatcher *certWatcherStruct) stop() {
+	certWatcher.stopOnce.Do(func() {
+		close(certWatcher.stopChan)
+		certWatcher.stopWG.Wait()
+		_ = certWatcher.watcher.Close()
+	})
+}
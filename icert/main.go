@@ -4,13 +4,17 @@
 package main
 
 import (
+	"bytes"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"time"
 
+	"github.com/NVIDIA/proxyfs/conf"
 	"github.com/NVIDIA/proxyfs/icert/icertpkg"
 )
 
@@ -27,12 +31,44 @@ func (sS *stringSlice) Set(s string) (err error) {
 	return
 }
 
+type issuanceSummaryStruct struct {
+	Template       string            `json:"template,omitempty"`
+	Resolved       map[string]string `json:"resolved,omitempty"`
+	Subject        string            `json:"subject"`
+	DNSNames       []string          `json:"dnsNames"`
+	IPAddresses    []string          `json:"ipAddresses"`
+	EmailAddresses []string          `json:"emailAddresses"`
+	URIs           []string          `json:"uris"`
+	CertFile       string            `json:"certFile"`
+	KeyFile        string            `json:"keyFile"`
+}
+
 func main() {
 	var (
 		verboseFlag = flag.Bool("v", false, "verbose mode")
 
 		caFlag = flag.Bool("ca", false, "generated CA Certicate usable for signing Endpoint Certificates")
 
+		overwriteFlag = flag.Bool("overwrite", false, "permit -ca to overwrite an existing CA Certificate's PrivateKey")
+
+		pathLenFlag = flag.Int("pathLen", -1, "maximum number of intermediate CAs that may follow a -ca Certificate (-1 is unlimited)")
+
+		clampToCAExpiryFlag = flag.Bool("clampToCAExpiry", false, "truncate an Endpoint Certificate outliving its CA Certificate to the CA's expiry")
+
+		jsonFlag = flag.Bool("json", false, "output a JSON summary of the generated Endpoint Certificate")
+
+		spiffeBundleFlag = flag.String("spiffeBundle", "", "path to which a SPIFFE trust bundle of the CA Certificate is written")
+		trustDomainFlag  = flag.String("trustDomain", "", "SPIFFE trust domain of -spiffeBundle")
+
+		lockFlag           = flag.Bool("lock", false, "serialize generation into the same output paths via an advisory lock file")
+		lockTimeoutFlag    = flag.Duration("lockTimeout", icertpkg.DefaultLockTimeout, "how long -lock awaits a lock held by another generator")
+		skipIfValidForFlag = flag.Duration("skipIfValidFor", time.Duration(0), "skip generation if the existing output remains valid for at least this long")
+
+		templateConfFlag = flag.String("templateConf", "", "path to .conf file defining [IssuanceTemplate:<template>] sections")
+		templateFlag     = flag.String("template", "", "name of issuance template specifying the Endpoint Certificate's Subject and SANs")
+		clusterFlag      = flag.String("cluster", "", "value of {{cluster}} in an issuance template")
+		envFlag          = flag.String("env", "", "value of {{env}} in an issuance template")
+
 		generateKeyAlgorithmEd25519Flag = flag.Bool(icertpkg.GenerateKeyAlgorithmEd25519, false, "generate key via Ed25519")
 		generateKeyAlgorithmRSAFlag     = flag.Bool(icertpkg.GenerateKeyAlgorithmRSA, false, "generate key via RSA")
 
@@ -45,8 +81,10 @@ func main() {
 
 		ttlFlag = flag.Duration("ttl", time.Duration(0), "generated Certificate's time to live")
 
-		dnsNamesFlag    stringSlice
-		ipAddressesFlag stringSlice
+		dnsNamesFlag       stringSlice
+		ipAddressesFlag    stringSlice
+		emailAddressesFlag stringSlice
+		urisFlag           stringSlice
 
 		caCertPemFilePathFlag = flag.String("caCert", "", "path to CA Certificate")
 		caKeyPemFilePathFlag  = flag.String("caKey", "", "path to CA Certificate's PrivateKey")
@@ -54,12 +92,34 @@ func main() {
 		endpointCertPemFilePathFlag = flag.String("cert", "", "path to Endpoint Certificate")
 		endpointKeyPemFilePathFlag  = flag.String("key", "", "path to Endpoint Certificate's PrivateKey")
 
+		certOptions          *icertpkg.CertOptions
+		confMap              conf.ConfMap
 		err                  error
+		expandedIssuance     *icertpkg.ExpandedIssuance
 		generateKeyAlgorithm string
 		ipAddresses          []net.IP
+		issuanceSummary      *issuanceSummaryStruct
+		issuanceSummaryJSON  []byte
+		issuanceTemplate     *icertpkg.IssuanceTemplate
+		skipped              bool
+		spiffeBundle         *bytes.Buffer
 		subject              pkix.Name
 	)
 
+	if 1 < len(os.Args) {
+		switch os.Args[1] {
+		case "show":
+			showMain(os.Args[2:])
+			return
+		case "verify":
+			verifyMain(os.Args[2:])
+			return
+		case "trust":
+			trustMain(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Var(&organizationFlag, "organization", "generated Certificate's Subject.Organization")
 	flag.Var(&countryFlag, "country", "generated Certificate's Subject.Country")
 	flag.Var(&provinceFlag, "province", "generated Certificate's Subject.Province")
@@ -69,11 +129,28 @@ func main() {
 
 	flag.Var(&dnsNamesFlag, "dns", "generated Certificate's DNS Name")
 	flag.Var(&ipAddressesFlag, "ip", "generated Certificate's IP Address")
+	flag.Var(&emailAddressesFlag, "email", "generated Certificate's Email Address")
+	flag.Var(&urisFlag, "uri", "generated Certificate's URI")
 
 	flag.Parse()
 
 	if *verboseFlag {
 		fmt.Printf("                         caFlag: %v\n", *caFlag)
+		fmt.Printf("                  overwriteFlag: %v\n", *overwriteFlag)
+		fmt.Printf("                    pathLenFlag: %v\n", *pathLenFlag)
+		fmt.Printf("            clampToCAExpiryFlag: %v\n", *clampToCAExpiryFlag)
+		fmt.Printf("                       jsonFlag: %v\n", *jsonFlag)
+		fmt.Println()
+		fmt.Printf("               spiffeBundleFlag: \"%v\"\n", *spiffeBundleFlag)
+		fmt.Printf("                trustDomainFlag: \"%v\"\n", *trustDomainFlag)
+		fmt.Printf("                       lockFlag: %v\n", *lockFlag)
+		fmt.Printf("                lockTimeoutFlag: %v\n", *lockTimeoutFlag)
+		fmt.Printf("             skipIfValidForFlag: %v\n", *skipIfValidForFlag)
+		fmt.Println()
+		fmt.Printf("               templateConfFlag: \"%v\"\n", *templateConfFlag)
+		fmt.Printf("                   templateFlag: \"%v\"\n", *templateFlag)
+		fmt.Printf("                    clusterFlag: \"%v\"\n", *clusterFlag)
+		fmt.Printf("                        envFlag: \"%v\"\n", *envFlag)
 		fmt.Println()
 		fmt.Printf("generateKeyAlgorithmEd25519Flag: %v\n", *generateKeyAlgorithmEd25519Flag)
 		fmt.Printf("    generateKeyAlgorithmRSAFlag: %v\n", *generateKeyAlgorithmRSAFlag)
@@ -89,6 +166,8 @@ func main() {
 		fmt.Println()
 		fmt.Printf("                   dnsNamesFlag: %v\n", dnsNamesFlag)
 		fmt.Printf("                ipAddressesFlag: %v\n", ipAddressesFlag)
+		fmt.Printf("             emailAddressesFlag: %v\n", emailAddressesFlag)
+		fmt.Printf("                       urisFlag: %v\n", urisFlag)
 		fmt.Println()
 		fmt.Printf("          caCertPemFilePathFlag: \"%v\"\n", *caCertPemFilePathFlag)
 		fmt.Printf("           caKeyPemFilePathFlag: \"%v\"\n", *caKeyPemFilePathFlag)
@@ -121,13 +200,47 @@ func main() {
 		os.Exit(1)
 	}
 
+	if ("" == *spiffeBundleFlag) != ("" == *trustDomainFlag) {
+		fmt.Printf("Either both or neither of -spiffeBundle and -trustDomain must be specified\n")
+		os.Exit(1)
+	}
+
 	if *caFlag {
 		if ("" != *endpointCertPemFilePathFlag) || ("" != *endpointKeyPemFilePathFlag) {
 			fmt.Printf("If -ca is specified, neither -cert nor -key may be specified\n")
 			os.Exit(1)
 		}
-		if (0 != len(dnsNamesFlag)) || (0 != len(ipAddressesFlag)) {
-			fmt.Printf("If -ca is specified, neither -dns nor -ip may be specified\n")
+		if (0 != len(dnsNamesFlag)) || (0 != len(ipAddressesFlag)) || (0 != len(emailAddressesFlag)) || (0 != len(urisFlag)) {
+			fmt.Printf("If -ca is specified, none of -dns, -ip, -email, or -uri may be specified\n")
+			os.Exit(1)
+		}
+		if ("" != *templateFlag) || *jsonFlag {
+			fmt.Printf("If -ca is specified, neither -template nor -json may be specified\n")
+			os.Exit(1)
+		}
+		if *clampToCAExpiryFlag {
+			fmt.Printf("If -ca is specified, -clampToCAExpiry may not be specified\n")
+			os.Exit(1)
+		}
+	} else if "" != *templateFlag {
+		if ("" == *endpointCertPemFilePathFlag) || ("" == *endpointKeyPemFilePathFlag) {
+			fmt.Printf("If -ca is not specified, both -cert and -key must be specified\n")
+			os.Exit(1)
+		}
+		if "" == *templateConfFlag {
+			fmt.Printf("If -template is specified, -templateConf must be specified\n")
+			os.Exit(1)
+		}
+		if (0 != len(organizationFlag)) || (0 != len(countryFlag)) || (0 != len(provinceFlag)) || (0 != len(localityFlag)) || (0 != len(streetAddressFlag)) || (0 != len(postalCodeFlag)) {
+			fmt.Printf("If -template is specified, the Subject may not be specified\n")
+			os.Exit(1)
+		}
+		if *overwriteFlag {
+			fmt.Printf("If -ca is not specified, -overwrite may not be specified\n")
+			os.Exit(1)
+		}
+		if -1 != *pathLenFlag {
+			fmt.Printf("If -ca is not specified, -pathLen may not be specified\n")
 			os.Exit(1)
 		}
 	} else {
@@ -135,8 +248,16 @@ func main() {
 			fmt.Printf("If -ca is not specified, both -cert and -key must be specified\n")
 			os.Exit(1)
 		}
-		if (0 == len(dnsNamesFlag)) && (0 == len(ipAddressesFlag)) {
-			fmt.Printf("If -ca is not specified, at least one -dns or -ip must be specified\n")
+		if (0 == len(dnsNamesFlag)) && (0 == len(ipAddressesFlag)) && (0 == len(emailAddressesFlag)) && (0 == len(urisFlag)) {
+			fmt.Printf("If -ca is not specified, at least one -dns, -ip, -email, or -uri must be specified\n")
+			os.Exit(1)
+		}
+		if *overwriteFlag {
+			fmt.Printf("If -ca is not specified, -overwrite may not be specified\n")
+			os.Exit(1)
+		}
+		if -1 != *pathLenFlag {
+			fmt.Printf("If -ca is not specified, -pathLen may not be specified\n")
 			os.Exit(1)
 		}
 	}
@@ -150,15 +271,29 @@ func main() {
 		PostalCode:    postalCodeFlag,
 	}
 
+	certOptions = &icertpkg.CertOptions{
+		Overwrite:       *overwriteFlag,
+		Constraints:     icertpkg.CAConstraints{MaxPathLen: *pathLenFlag, MaxPathLenZero: (0 == *pathLenFlag)},
+		ClampToCAExpiry: *clampToCAExpiryFlag,
+		Lock:            *lockFlag,
+		LockTimeout:     *lockTimeoutFlag,
+		SkipIfValidFor:  *skipIfValidForFlag,
+		OnSkip:          func() { skipped = true },
+	}
+
 	if *caFlag {
-		err = icertpkg.GenCACert(generateKeyAlgorithm, subject, *ttlFlag, *caCertPemFilePathFlag, *caKeyPemFilePathFlag)
+		err = icertpkg.GenCACertWithOptions(generateKeyAlgorithm, subject, *ttlFlag, *caCertPemFilePathFlag, *caKeyPemFilePathFlag, certOptions)
 		if nil != err {
-			fmt.Printf("icertpkg.GenCACert() failed: %v\n", err)
+			fmt.Printf("icertpkg.GenCACertWithOptions() failed: %v\n", err)
 			os.Exit(1)
 		}
 
 		if *verboseFlag {
-			fmt.Printf("icertpkg.GenCACert() generated caCert: \"%s\" and caKey: \"%s\"\n", *caCertPemFilePathFlag, *caKeyPemFilePathFlag)
+			if skipped {
+				fmt.Printf("icertpkg.GenCACertWithOptions() skipped as caCert: \"%s\" and caKey: \"%s\" remain valid\n", *caCertPemFilePathFlag, *caKeyPemFilePathFlag)
+			} else {
+				fmt.Printf("icertpkg.GenCACertWithOptions() generated caCert: \"%s\" and caKey: \"%s\"\n", *caCertPemFilePathFlag, *caKeyPemFilePathFlag)
+			}
 		}
 	} else {
 		ipAddresses = make([]net.IP, 0, len(ipAddressesFlag))
@@ -167,14 +302,93 @@ func main() {
 			ipAddresses = append(ipAddresses, net.ParseIP(ipAddress))
 		}
 
-		err = icertpkg.GenEndpointCert(generateKeyAlgorithm, subject, dnsNamesFlag, ipAddresses, *ttlFlag, *caCertPemFilePathFlag, *caKeyPemFilePathFlag, *endpointCertPemFilePathFlag, *endpointKeyPemFilePathFlag)
+		if "" == *templateFlag {
+			expandedIssuance = &icertpkg.ExpandedIssuance{Subject: subject}
+		} else {
+			confMap, err = conf.MakeConfMapFromFile(*templateConfFlag)
+			if nil != err {
+				fmt.Printf("conf.MakeConfMapFromFile() failed: %v\n", err)
+				os.Exit(1)
+			}
+
+			issuanceTemplate, err = icertpkg.LoadIssuanceTemplate(confMap, *templateFlag)
+			if nil != err {
+				fmt.Printf("icertpkg.LoadIssuanceTemplate() failed: %v\n", err)
+				os.Exit(1)
+			}
+
+			expandedIssuance, err = icertpkg.ExpandIssuanceTemplate(issuanceTemplate, icertpkg.NewHostTemplateResolver(*clusterFlag, *envFlag))
+			if nil != err {
+				fmt.Printf("icertpkg.ExpandIssuanceTemplate() failed: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		expandedIssuance.AppendSANs(dnsNamesFlag, ipAddresses, emailAddressesFlag, urisFlag)
+
+		if (0 == len(expandedIssuance.DNSNames)) && (0 == len(expandedIssuance.IPAddresses)) && (0 == len(expandedIssuance.EmailAddresses)) && (0 == len(expandedIssuance.URIs)) {
+			fmt.Printf("-template \"%s\" specifies no SANs and no -dns, -ip, -email, or -uri was specified\n", *templateFlag)
+			os.Exit(1)
+		}
+
+		err = icertpkg.GenEndpointCertWithOptions(generateKeyAlgorithm, expandedIssuance.Subject, expandedIssuance.DNSNames, expandedIssuance.IPAddresses, expandedIssuance.EmailAddresses, expandedIssuance.URIs, *ttlFlag, *caCertPemFilePathFlag, *caKeyPemFilePathFlag, *endpointCertPemFilePathFlag, *endpointKeyPemFilePathFlag, certOptions)
+		if nil != err {
+			fmt.Printf("icertpkg.GenEndpointCertWithOptions() failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *verboseFlag {
+			if skipped {
+				fmt.Printf("icertpkg.GenEndpointCertWithOptions() skipped as cert: \"%s\" and key: \"%s\" remain valid\n", *endpointCertPemFilePathFlag, *endpointKeyPemFilePathFlag)
+			} else {
+				fmt.Printf("icertpkg.GenEndpointCertWithOptions() generated cert: \"%s\" and key: \"%s\"\n", *endpointCertPemFilePathFlag, *endpointKeyPemFilePathFlag)
+			}
+		}
+
+		if *jsonFlag {
+			issuanceSummary = &issuanceSummaryStruct{
+				Template:       *templateFlag,
+				Resolved:       expandedIssuance.Resolved,
+				Subject:        expandedIssuance.Subject.String(),
+				DNSNames:       expandedIssuance.DNSNames,
+				IPAddresses:    make([]string, 0, len(expandedIssuance.IPAddresses)),
+				EmailAddresses: expandedIssuance.EmailAddresses,
+				URIs:           expandedIssuance.URIs,
+				CertFile:       *endpointCertPemFilePathFlag,
+				KeyFile:        *endpointKeyPemFilePathFlag,
+			}
+
+			for _, ipAddress := range expandedIssuance.IPAddresses {
+				issuanceSummary.IPAddresses = append(issuanceSummary.IPAddresses, ipAddress.String())
+			}
+
+			issuanceSummaryJSON, err = json.MarshalIndent(issuanceSummary, "", "  ")
+			if nil != err {
+				fmt.Printf("json.MarshalIndent() failed: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Println(string(issuanceSummaryJSON))
+		}
+	}
+
+	if "" != *spiffeBundleFlag {
+		spiffeBundle = &bytes.Buffer{}
+
+		err = icertpkg.ExportSPIFFEBundle([]string{*caCertPemFilePathFlag}, *trustDomainFlag, spiffeBundle)
+		if nil != err {
+			fmt.Printf("icertpkg.ExportSPIFFEBundle() failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		err = ioutil.WriteFile(*spiffeBundleFlag, spiffeBundle.Bytes(), icertpkg.GeneratedFilePerm)
 		if nil != err {
-			fmt.Printf("icertpkg.GenEndpointCert() failed: %v\n", err)
+			fmt.Printf("ioutil.WriteFile(\"%s\") failed: %v\n", *spiffeBundleFlag, err)
 			os.Exit(1)
 		}
 
 		if *verboseFlag {
-			fmt.Printf("icertpkg.GenEndpointCert() generated cert: \"%s\" and key: \"%s\"\n", *endpointCertPemFilePathFlag, *endpointKeyPemFilePathFlag)
+			fmt.Printf("icertpkg.ExportSPIFFEBundle() wrote spiffeBundle: \"%s\"\n", *spiffeBundleFlag)
 		}
 	}
 }
@@ -0,0 +1,110 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/proxyfs/icert/icertpkg"
+)
+
+// showMain implements `icert show [-json] <pem>...` summarizing every
+// Certificate in each (cert, combined, or chain) PEM file.
+//
+func showMain(args []string) {
+	var (
+		certInfos    []*icertpkg.CertInfo
+		err          error
+		flagSet      *flag.FlagSet
+		jsonFlag     *bool
+		pemPath      string
+		showInfos    []*icertpkg.CertInfo
+		showInfoJSON []byte
+	)
+
+	flagSet = flag.NewFlagSet("show", flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "usage: %s show [-json] <pem>...\n", os.Args[0])
+		flagSet.PrintDefaults()
+	}
+
+	jsonFlag = flagSet.Bool("json", false, "output a JSON array summarizing each Certificate")
+
+	_ = flagSet.Parse(args)
+
+	if 0 == flagSet.NArg() {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	showInfos = make([]*icertpkg.CertInfo, 0)
+
+	for _, pemPath = range flagSet.Args() {
+		certInfos, err = icertpkg.GetCertChainInfo(pemPath)
+		if nil != err {
+			fmt.Printf("icertpkg.GetCertChainInfo(\"%s\") failed: %v\n", pemPath, err)
+			os.Exit(1)
+		}
+
+		showInfos = append(showInfos, certInfos...)
+	}
+
+	if *jsonFlag {
+		showInfoJSON, err = json.MarshalIndent(showInfos, "", "  ")
+		if nil != err {
+			fmt.Printf("json.MarshalIndent() failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(showInfoJSON))
+
+		return
+	}
+
+	for i, certInfo := range showInfos {
+		if 0 != i {
+			fmt.Println()
+		}
+		printCertInfo(certInfo)
+	}
+}
+
+func printCertInfo(certInfo *icertpkg.CertInfo) {
+	var (
+		ipAddresses []string
+		keyType     string
+	)
+
+	keyType = fmt.Sprintf("%s %d", certInfo.KeyAlgorithm, certInfo.KeySize)
+	if "" != certInfo.KeyCurve {
+		keyType += " " + certInfo.KeyCurve
+	}
+
+	ipAddresses = make([]string, 0, len(certInfo.IPAddresses))
+	for _, ipAddress := range certInfo.IPAddresses {
+		ipAddresses = append(ipAddresses, ipAddress.String())
+	}
+
+	fmt.Printf("           Subject: %s\n", certInfo.Subject)
+	fmt.Printf("            Issuer: %s\n", certInfo.Issuer)
+	fmt.Printf("      SerialNumber: %s\n", certInfo.SerialNumber)
+	fmt.Printf("         NotBefore: %s\n", certInfo.NotBefore.Format(time.RFC3339))
+	fmt.Printf("          NotAfter: %s\n", certInfo.NotAfter.Format(time.RFC3339))
+	fmt.Printf(" RemainingValidity: %v\n", certInfo.RemainingValidity.Round(time.Second))
+	fmt.Printf("               Key: %s\n", keyType)
+	fmt.Printf("SignatureAlgorithm: %s\n", certInfo.SignatureAlgorithm)
+	fmt.Printf("       Fingerprint: %s\n", certInfo.Fingerprint)
+	fmt.Printf("          DNSNames: %s\n", strings.Join(certInfo.DNSNames, ", "))
+	fmt.Printf("       IPAddresses: %s\n", strings.Join(ipAddresses, ", "))
+	fmt.Printf("    EmailAddresses: %s\n", strings.Join(certInfo.EmailAddresses, ", "))
+	fmt.Printf("              URIs: %s\n", strings.Join(certInfo.URIs, ", "))
+	fmt.Printf("              IsCA: %v\n", certInfo.IsCA)
+	fmt.Printf("          KeyUsage: %s\n", strings.Join(certInfo.KeyUsage, ", "))
+	fmt.Printf("       ExtKeyUsage: %s\n", strings.Join(certInfo.ExtKeyUsage, ", "))
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/NVIDIA/proxyfs/icert/icertpkg"
+)
+
+// trustMain implements `icert trust {install|remove} -yes [-platform <platform>]
+// <ca.pem>` adding the CA Certificate in <ca.pem> to (or removing it from) the
+// system trust store.
+//
+func trustMain(args []string) {
+	var (
+		err          error
+		flagSet      *flag.FlagSet
+		platformFlag *string
+		trustOptions *icertpkg.TrustOptions
+		yesFlag      *bool
+	)
+
+	flagSet = flag.NewFlagSet("trust", flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "usage: %s trust {install|remove} -yes [-platform <platform>] <ca.pem>\n", os.Args[0])
+		flagSet.PrintDefaults()
+	}
+
+	yesFlag = flagSet.Bool("yes", false, "confirm modifying the system trust store")
+	platformFlag = flagSet.String("platform", "", fmt.Sprintf("system trust store (one of %s, %s, %s, or %s) if not detected", icertpkg.TrustPlatformDebian, icertpkg.TrustPlatformRHEL, icertpkg.TrustPlatformDarwin, icertpkg.TrustPlatformWindows))
+
+	if (0 == len(args)) || (("install" != args[0]) && ("remove" != args[0])) {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	_ = flagSet.Parse(args[1:])
+
+	if 1 != flagSet.NArg() {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	trustOptions = &icertpkg.TrustOptions{
+		Confirm:  *yesFlag,
+		Platform: *platformFlag,
+	}
+
+	if "install" == args[0] {
+		err = icertpkg.InstallCAToSystemTrust(flagSet.Arg(0), trustOptions)
+	} else {
+		err = icertpkg.RemoveCAFromSystemTrust(flagSet.Arg(0), trustOptions)
+	}
+	if nil != err {
+		if errors.Is(err, icertpkg.ErrTrustNotConfirmed) {
+			fmt.Printf("Modifying the system trust store requires -yes\n")
+		} else {
+			fmt.Printf("icert trust %s failed: %v\n", args[0], err)
+		}
+		os.Exit(1)
+	}
+
+	if "install" == args[0] {
+		fmt.Printf("\"%s\" is trusted by the system trust store\n", flagSet.Arg(0))
+	} else {
+		fmt.Printf("\"%s\" is no longer trusted by the system trust store\n", flagSet.Arg(0))
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NVIDIA/proxyfs/icert/icertpkg"
+)
+
+type verifySummaryStruct struct {
+	CertFile string `json:"certFile"`
+	CAFile   string `json:"caFile"`
+	Name     string `json:"name"`
+	At       string `json:"at"`
+	Valid    bool   `json:"valid"`
+	Reason   string `json:"reason,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// verifyMain implements `icert verify -ca <ca.pem> -name <host> [-at <time>]
+// [-json] <cert.pem>` exiting with status 0 if valid, 2 if verification fails,
+// or 1 on any other error.
+//
+func verifyMain(args []string) {
+	var (
+		at                time.Time
+		atFlag            *string
+		caFlag            *string
+		err               error
+		flagSet           *flag.FlagSet
+		jsonFlag          *bool
+		nameFlag          *string
+		verifySummary     *verifySummaryStruct
+		verifySummaryJSON []byte
+	)
+
+	flagSet = flag.NewFlagSet("verify", flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "usage: %s verify -ca <ca.pem> -name <host> [-at <time>] [-json] <cert.pem>\n", os.Args[0])
+		flagSet.PrintDefaults()
+	}
+
+	caFlag = flagSet.String("ca", "", "path to CA Certificate(s) to trust")
+	nameFlag = flagSet.String("name", "", "DNS Name or IP Address the Certificate must be valid for")
+	atFlag = flagSet.String("at", "", "RFC 3339 time at which to verify (default now)")
+	jsonFlag = flagSet.Bool("json", false, "output a JSON summary of the verification")
+
+	_ = flagSet.Parse(args)
+
+	if ("" == *caFlag) || ("" == *nameFlag) || (1 != flagSet.NArg()) {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	if "" == *atFlag {
+		at = time.Now()
+	} else {
+		at, err = time.Parse(time.RFC3339, *atFlag)
+		if nil != err {
+			fmt.Printf("-at \"%s\" is not an RFC 3339 time: %v\n", *atFlag, err)
+			os.Exit(1)
+		}
+	}
+
+	verifySummary = &verifySummaryStruct{
+		CertFile: flagSet.Arg(0),
+		CAFile:   *caFlag,
+		Name:     *nameFlag,
+		At:       at.Format(time.RFC3339),
+	}
+
+	err = icertpkg.VerifyEndpointCert(verifySummary.CertFile, verifySummary.CAFile, verifySummary.Name, at)
+	if nil == err {
+		verifySummary.Valid = true
+	} else {
+		verifySummary.Reason = verifyFailureReason(err)
+		verifySummary.Error = err.Error()
+	}
+
+	if *jsonFlag {
+		verifySummaryJSON, err = json.MarshalIndent(verifySummary, "", "  ")
+		if nil != err {
+			fmt.Printf("json.MarshalIndent() failed: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(verifySummaryJSON))
+	} else if verifySummary.Valid {
+		fmt.Printf("OK: \"%s\" chains to \"%s\" and is valid for \"%s\" at %s\n", verifySummary.CertFile, verifySummary.CAFile, verifySummary.Name, verifySummary.At)
+	} else {
+		fmt.Printf("FAILED (%s): %s\n", verifySummary.Reason, verifySummary.Error)
+	}
+
+	switch verifySummary.Reason {
+	case "":
+		// Valid
+	case "error":
+		os.Exit(1)
+	default:
+		os.Exit(2)
+	}
+}
+
+func verifyFailureReason(err error) (reason string) {
+	switch {
+	case errors.Is(err, icertpkg.ErrCertExpired):
+		reason = "expired"
+	case errors.Is(err, icertpkg.ErrCertNotYetValid):
+		reason = "not-yet-valid"
+	case errors.Is(err, icertpkg.ErrUnknownAuthority):
+		reason = "unknown-authority"
+	case errors.Is(err, icertpkg.ErrNameNotPermitted):
+		reason = "name-not-permitted"
+	case errors.Is(err, icertpkg.ErrPathLenExceeded):
+		reason = "path-len-exceeded"
+	case errors.Is(err, icertpkg.ErrNameMismatch):
+		reason = "name-mismatch"
+	default:
+		reason = "error"
+	}
+
+	return
+}
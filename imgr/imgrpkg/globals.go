@@ -90,6 +90,9 @@ type statsStruct struct {
 
 	InodeTableCacheHits   bucketstats.Totaler
 	InodeTableCacheMisses bucketstats.Totaler
+
+	SlowestRPCs          bucketstats.SlowestN // Keyed by "<volumeName>/<inodeNumber>"
+	SlowestSwiftRequests bucketstats.SlowestN // Keyed by "<volumeName>/<objectNumber>"
 }
 
 type globalsStruct struct {
@@ -104,6 +104,8 @@ func serveHTTPGet(responseWriter http.ResponseWriter, request *http.Request) {
 		serveHTTPGetOfConfig(responseWriter, request)
 	case "/stats" == path:
 		serveHTTPGetOfStats(responseWriter, request)
+	case "/stats/slowest" == path:
+		serveHTTPGetOfStatsSlowest(responseWriter, request)
 	case strings.HasPrefix(path, "/volume"):
 		serveHTTPGetOfVolume(responseWriter, request)
 	default:
@@ -154,6 +156,32 @@ func serveHTTPGetOfStats(responseWriter http.ResponseWriter, request *http.Reque
 	}
 }
 
+func serveHTTPGetOfStatsSlowest(responseWriter http.ResponseWriter, request *http.Request) {
+	var (
+		err         error
+		slowestJSON []byte
+	)
+
+	slowestJSON, err = json.Marshal(struct {
+		SlowestRPCs          []bucketstats.SlowestSample
+		SlowestSwiftRequests []bucketstats.SlowestSample
+	}{
+		SlowestRPCs:          globals.stats.SlowestRPCs.SlowestGet(),
+		SlowestSwiftRequests: globals.stats.SlowestSwiftRequests.SlowestGet(),
+	})
+	if nil != err {
+		logFatalf("json.Marshal(slowest) failed: %v", err)
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	_, err = responseWriter.Write(slowestJSON)
+	if nil != err {
+		logWarnf("responseWriter.Write(slowestJSON) failed: %v", err)
+	}
+}
+
 func serveHTTPPut(responseWriter http.ResponseWriter, request *http.Request) {
 	var (
 		path string
@@ -0,0 +1,74 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package imgrpkg
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/proxyfs/bucketstats"
+)
+
+func TestHTTPServerGetOfStatsSlowest(t *testing.T) {
+	var (
+		err            error
+		request        *http.Request
+		responseWriter *httptest.ResponseRecorder
+		savedStats     *statsStruct
+		slowest        struct {
+			SlowestRPCs          []bucketstats.SlowestSample
+			SlowestSwiftRequests []bucketstats.SlowestSample
+		}
+	)
+
+	savedStats = globals.stats
+	defer func() {
+		globals.stats = savedStats
+	}()
+
+	globals.stats = &statsStruct{
+		SlowestRPCs:          bucketstats.SlowestN{N: 2},
+		SlowestSwiftRequests: bucketstats.SlowestN{N: 2},
+	}
+
+	globals.stats.SlowestRPCs.Add("volumeA/1", 10*time.Millisecond)
+	globals.stats.SlowestRPCs.Add("volumeA/2", 30*time.Millisecond)
+	globals.stats.SlowestRPCs.Add("volumeB/3", 20*time.Millisecond)
+
+	globals.stats.SlowestSwiftRequests.Add("volumeA/0000000000000011", 5*time.Millisecond)
+
+	request = httptest.NewRequest(http.MethodGet, "/stats/slowest", nil)
+	responseWriter = httptest.NewRecorder()
+
+	serveHTTPGetOfStatsSlowest(responseWriter, request)
+
+	if http.StatusOK != responseWriter.Code {
+		t.Fatalf("GET /stats/slowest returned status %d, expected %d", responseWriter.Code, http.StatusOK)
+	}
+	if "application/json" != responseWriter.Header().Get("Content-Type") {
+		t.Fatalf("GET /stats/slowest returned Content-Type \"%s\"", responseWriter.Header().Get("Content-Type"))
+	}
+
+	err = json.Unmarshal(responseWriter.Body.Bytes(), &slowest)
+	if nil != err {
+		t.Fatalf("json.Unmarshal() of GET /stats/slowest response failed: %v", err)
+	}
+
+	// Only the N slowest are returned, slowest first
+
+	if (2 != len(slowest.SlowestRPCs)) ||
+		("volumeA/2" != slowest.SlowestRPCs[0].Key) || (30*time.Millisecond != slowest.SlowestRPCs[0].Duration) ||
+		("volumeB/3" != slowest.SlowestRPCs[1].Key) || (20*time.Millisecond != slowest.SlowestRPCs[1].Duration) {
+		t.Fatalf("GET /stats/slowest returned SlowestRPCs %v", slowest.SlowestRPCs)
+	}
+	if (1 != len(slowest.SlowestSwiftRequests)) || ("volumeA/0000000000000011" != slowest.SlowestSwiftRequests[0].Key) {
+		t.Fatalf("GET /stats/slowest returned SlowestSwiftRequests %v", slowest.SlowestSwiftRequests)
+	}
+	if slowest.SlowestRPCs[0].Time.IsZero() {
+		t.Fatalf("GET /stats/slowest returned a sample lacking its Time")
+	}
+}
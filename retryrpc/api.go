@@ -12,12 +12,13 @@ package retryrpc
 
 import (
 	"container/list"
-	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"net"
+	"os"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +27,28 @@ import (
 	"github.com/google/btree"
 )
 
+// UnixSocketScheme prefixes the IPAddr of a ServerConfig or ClientConfig to
+// select a unix domain socket transport (e.g. "unix:///run/proxyfs/imgr.sock")
+// rather than TCP+TLS. No Port nor TLS credentials are used with such an IPAddr.
+const UnixSocketScheme = "unix://"
+
+// DefaultUnixSocketPerm is the mode given a Server's unix domain socket if
+// ServerConfig.UnixSocketPerm is not specified
+const DefaultUnixSocketPerm os.FileMode = 0600
+
+// PeerCreds holds the credentials (captured via SO_PEERCRED where supported) of
+// the process on the other end of a unix domain socket connection.
+//
+// A registered method taking a *PeerCreds ahead of its request and reply (i.e.
+// Method(peerCreds *PeerCreds, request *Request, reply *Reply) error) is passed
+// those of the connection on which the request arrived. This takes the place of
+// the TLS identity of a TCP connection, for which peerCreds is nil.
+type PeerCreds struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
 // ServerCreds tracks the root CA and the
 // server CA
 type ServerCreds struct {
@@ -41,8 +64,11 @@ type Server struct {
 	svrMap           map[string]*methodArgs // Key: Method name
 	ipaddr           string                 // IP address server listens too
 	port             int                    // Port of server
+	unixSocketPath   string                 // If non-empty, unix domain socket server listens on instead
+	unixSocketPerm   os.FileMode            // Mode of unixSocketPath
 	netListener      net.Listener
 	tlsListener      net.Listener
+	listener         net.Listener // tlsListener or, for a unix domain socket, netListener
 
 	halting              bool
 	goroutineWG          sync.WaitGroup // Used to track outstanding goroutines
@@ -66,14 +92,17 @@ type Server struct {
 type ServerConfig struct {
 	LongTrim          time.Duration // How long the results of an RPC are stored on a Server before removed
 	ShortTrim         time.Duration // How frequently completed and ACKed RPCs results are removed from Server
-	IPAddr            string        // IP Address that Server uses to listen
+	IPAddr            string        // IP Address that Server uses to listen (or UnixSocketScheme followed by a socket path)
 	Port              int           // Port that Server uses to listen
+	UnixSocketPerm    os.FileMode   // Mode of a unix domain socket (DefaultUnixSocketPerm if zero)
 	DeadlineIO        time.Duration // How long I/Os on sockets wait even if idle
 	KeepAlivePeriod   time.Duration // How frequently a KEEPALIVE is sent
 	dontStartTrimmers bool          // Used for testing
 }
 
 // NewServer creates the Server object
+//
+// A Server listening on a unix domain socket has no Creds.
 func NewServer(config *ServerConfig) *Server {
 	var (
 		err error
@@ -86,6 +115,15 @@ func NewServer(config *ServerConfig) *Server {
 	server.completedTickerDone = make(chan bool)
 	server.connections = list.New()
 
+	if strings.HasPrefix(config.IPAddr, UnixSocketScheme) {
+		server.unixSocketPath = strings.TrimPrefix(config.IPAddr, UnixSocketScheme)
+		server.unixSocketPerm = config.UnixSocketPerm
+		if server.unixSocketPerm == 0 {
+			server.unixSocketPerm = DefaultUnixSocketPerm
+		}
+		return server
+	}
+
 	server.Creds, err = constructServerCreds(server.ipaddr)
 	if err != nil {
 		logger.Errorf("Construction of server credentials failed with err: %v", err)
@@ -105,22 +143,23 @@ func (server *Server) Register(retrySvr interface{}) (err error) {
 
 // Start listener
 func (server *Server) Start() (err error) {
-	portStr := fmt.Sprintf("%d", server.port)
-	hostPortStr := net.JoinHostPort(server.ipaddr, portStr)
-
-	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{server.Creds.serverTLSCertificate},
-	}
-
-	listenConfig := &net.ListenConfig{KeepAlive: server.keepAlivePeriod}
-	server.netListener, err = listenConfig.Listen(context.Background(), "tcp", hostPortStr)
-	if nil != err {
-		err = fmt.Errorf("tls.Listen() failed: %v", err)
-		return
+	if server.unixSocketPath != "" {
+		// A unix domain socket has no Port (just as NewClient() insists)
+		if server.port != 0 {
+			err = fmt.Errorf("Port not supported for unix domain socket IPAddr %s", server.ipaddr)
+			return
+		}
+		err = server.listenUnixSocket()
+		if nil != err {
+			return
+		}
+	} else {
+		err = server.listenTLS()
+		if nil != err {
+			return
+		}
 	}
 
-	server.tlsListener = tls.NewListener(server.netListener, tlsConfig)
-
 	server.listenersWG.Add(1)
 
 	// Some of the unit tests disable starting trimmers
@@ -206,9 +245,15 @@ func (server *Server) Close() {
 	server.halting = true
 	server.Unlock()
 
-	err := server.tlsListener.Close()
+	err := server.listener.Close()
 	if err != nil {
-		logger.Errorf("server.tlsListener.Close() returned err: %v", err)
+		logger.Errorf("server.listener.Close() returned err: %v", err)
+	}
+	if server.unixSocketPath != "" {
+		err = os.Remove(server.unixSocketPath)
+		if err != nil {
+			logger.Errorf("os.Remove(%s) returned err: %v", server.unixSocketPath, err)
+		}
 	}
 
 	server.listenersWG.Wait()
@@ -278,12 +323,13 @@ const (
 
 type connectionTracker struct {
 	state                    clientState
-	genNum                   uint64 // Generation number of tlsConn - avoid racing recoveries
+	genNum                   uint64 // Generation number of conn - avoid racing recoveries
 	tlsConfig                *tls.Config
-	tlsConn                  *tls.Conn // Our connection to the server
+	conn                     net.Conn // Our connection to the server
 	x509CertPool             *x509.CertPool
 	rootCAx509CertificatePEM []byte
 	hostPortStr              string
+	unixSocketPath           string // If non-empty, dial this unix domain socket rather than hostPortStr
 }
 
 // Client tracking structure
@@ -316,9 +362,9 @@ type ClientCallbacks interface {
 // ClientConfig is used to configure a retryrpc Client
 type ClientConfig struct {
 	MyUniqueID               string
-	IPAddr                   string        // IP Address of Server
+	IPAddr                   string        // IP Address of Server (or UnixSocketScheme followed by a socket path)
 	Port                     int           // Port of Server
-	RootCAx509CertificatePEM []byte        // Root certificate
+	RootCAx509CertificatePEM []byte        // Root certificate (must be empty for a unix domain socket)
 	Callbacks                interface{}   // Structure implementing ClientCallbacks
 	DeadlineIO               time.Duration // How long I/Os on sockets wait even if idle
 	KeepAlivePeriod          time.Duration // How frequently a KEEPALIVE is sent
@@ -341,18 +387,32 @@ func NewClient(config *ClientConfig) (client *Client, err error) {
 
 	client = &Client{myUniqueID: config.MyUniqueID, cb: config.Callbacks,
 		keepAlivePeriod: config.KeepAlivePeriod, deadlineIO: config.DeadlineIO}
-	portStr := fmt.Sprintf("%d", config.Port)
 	client.connection.state = INITIAL
-	client.connection.hostPortStr = net.JoinHostPort(config.IPAddr, portStr)
 	client.outstandingRequest = make(map[requestID]*reqCtx)
-	client.connection.x509CertPool = x509.NewCertPool()
 	client.bt = btree.New(2)
 
-	// Add cert for root CA to our pool
-	ok := client.connection.x509CertPool.AppendCertsFromPEM(config.RootCAx509CertificatePEM)
-	if !ok {
-		err = fmt.Errorf("x509CertPool.AppendCertsFromPEM() returned !ok")
-		return nil, err
+	if strings.HasPrefix(config.IPAddr, UnixSocketScheme) {
+		// A unix domain socket is neither secured by TLS nor has a Port
+		if len(config.RootCAx509CertificatePEM) != 0 {
+			err = fmt.Errorf("RootCAx509CertificatePEM not supported for unix domain socket IPAddr %s", config.IPAddr)
+			return nil, err
+		}
+		if config.Port != 0 {
+			err = fmt.Errorf("Port not supported for unix domain socket IPAddr %s", config.IPAddr)
+			return nil, err
+		}
+		client.connection.unixSocketPath = strings.TrimPrefix(config.IPAddr, UnixSocketScheme)
+	} else {
+		portStr := fmt.Sprintf("%d", config.Port)
+		client.connection.hostPortStr = net.JoinHostPort(config.IPAddr, portStr)
+		client.connection.x509CertPool = x509.NewCertPool()
+
+		// Add cert for root CA to our pool
+		ok := client.connection.x509CertPool.AppendCertsFromPEM(config.RootCAx509CertificatePEM)
+		if !ok {
+			err = fmt.Errorf("x509CertPool.AppendCertsFromPEM() returned !ok")
+			return nil, err
+		}
 	}
 
 	bucketstats.Register("proxyfs.retryrpc", client.GetStatsGroupName(), &client.stats)
@@ -380,7 +440,7 @@ func (client *Client) Close() {
 	client.halting = true
 	if client.connection.state == CONNECTED {
 		client.connection.state = INITIAL
-		client.connection.tlsConn.Close()
+		client.connection.conn.Close()
 	}
 	client.Unlock()
 
@@ -85,6 +85,7 @@ type connCtx struct {
 	cond                *sync.Cond     // Signal waiting goroutines that serviceClient() has exited
 	serviceClientExited bool
 	ci                  *clientInfo // Back pointer to the CI
+	peerCreds           *PeerCreds  // Peer of a unix domain socket connection (else nil)
 }
 
 // pendingCtx tracks an individual request from a client
@@ -97,9 +98,10 @@ type pendingCtx struct {
 // methodArgs defines the method provided by the RPC server
 // as well as the request type and reply type arguments
 type methodArgs struct {
-	methodPtr *reflect.Method
-	request   reflect.Type
-	reply     reflect.Type
+	methodPtr     *reflect.Method
+	request       reflect.Type
+	reply         reflect.Type
+	passPeerCreds bool // Method takes the *PeerCreds of the connection ahead of request
 }
 
 // completedLRUEntry tracks time entry was completed for
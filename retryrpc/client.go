@@ -163,8 +163,8 @@ func (client *Client) sendToServer(crID requestID, ctx *reqCtx, queue bool) {
 	}
 
 	// Send header
-	client.connection.tlsConn.SetDeadline(time.Now().Add(client.deadlineIO))
-	err := binary.Write(client.connection.tlsConn, binary.BigEndian, ctx.ioreq.Hdr)
+	client.connection.conn.SetDeadline(time.Now().Add(client.deadlineIO))
+	err := binary.Write(client.connection.conn, binary.BigEndian, ctx.ioreq.Hdr)
 	if err != nil {
 		genNum := ctx.genNum
 		client.Unlock()
@@ -176,8 +176,8 @@ func (client *Client) sendToServer(crID requestID, ctx *reqCtx, queue bool) {
 	}
 
 	// Send JSON request
-	client.connection.tlsConn.SetDeadline(time.Now().Add(client.deadlineIO))
-	bytesWritten, writeErr := client.connection.tlsConn.Write(ctx.ioreq.JReq)
+	client.connection.conn.SetDeadline(time.Now().Add(client.deadlineIO))
+	bytesWritten, writeErr := client.connection.conn.Write(ctx.ioreq.JReq)
 
 	if (bytesWritten != len(ctx.ioreq.JReq)) || (writeErr != nil) {
 		/* TODO - log message?
@@ -268,14 +268,14 @@ func (client *Client) notifyReply(buf []byte, genNum uint64) {
 //
 // As soon as it reads a complete response, it launches a goroutine to process
 // the response and notify the blocked Send().
-func (client *Client) readReplies(callingGenNum uint64, tlsConn *tls.Conn) {
+func (client *Client) readReplies(callingGenNum uint64, conn net.Conn) {
 	defer client.goroutineWG.Done()
 	var localCnt int
 
 	for {
 
 		// Wait reply from server
-		buf, msgType, getErr := getIO(callingGenNum, client.deadlineIO, tlsConn)
+		buf, msgType, getErr := getIO(callingGenNum, client.deadlineIO, conn)
 
 		// This must happen before checking error
 		client.Lock()
@@ -356,7 +356,7 @@ func (client *Client) retransmit(genNum uint64) {
 
 	// We are the first goroutine to notice the error on the
 	// socket - close the connection and start trying to reconnect.
-	_ = client.connection.tlsConn.Close()
+	_ = client.connection.conn.Close()
 	client.connection.state = RETRANSMITTING
 	client.stats.RetransmitsStarted.Add(1)
 
@@ -398,7 +398,7 @@ func (client *Client) retransmit(genNum uint64) {
 // Send myUniqueID to server
 //
 // NOTE: Client lock is already held during this call.
-func (client *Client) sendMyInfo(tlsConn *tls.Conn) (err error) {
+func (client *Client) sendMyInfo(conn net.Conn) (err error) {
 
 	// Setup ioreq to write structure on socket to server
 	isreq, err := buildSetIDRequest(client.myUniqueID)
@@ -409,15 +409,15 @@ func (client *Client) sendMyInfo(tlsConn *tls.Conn) (err error) {
 	}
 
 	// Send header
-	client.connection.tlsConn.SetDeadline(time.Now().Add(client.deadlineIO))
-	err = binary.Write(tlsConn, binary.BigEndian, isreq.Hdr)
+	client.connection.conn.SetDeadline(time.Now().Add(client.deadlineIO))
+	err = binary.Write(conn, binary.BigEndian, isreq.Hdr)
 	if err != nil {
 		return
 	}
 
 	// Send MyUniqueID
-	client.connection.tlsConn.SetDeadline(time.Now().Add(client.deadlineIO))
-	bytesWritten, writeErr := tlsConn.Write(isreq.MyUniqueID)
+	client.connection.conn.SetDeadline(time.Now().Add(client.deadlineIO))
+	bytesWritten, writeErr := conn.Write(isreq.MyUniqueID)
 
 	if uint32(bytesWritten) != isreq.Hdr.Len {
 		e := fmt.Errorf("sendMyInfo length incorrect")
@@ -440,42 +440,54 @@ func (client *Client) sendMyInfo(tlsConn *tls.Conn) (err error) {
 //
 // NOTE: Client lock is held
 func (client *Client) dial() (err error) {
-	var entryState = client.connection.state
-
-	client.connection.tlsConfig = &tls.Config{
-		RootCAs: client.connection.x509CertPool,
-	}
+	var (
+		conn       net.Conn
+		dialErr    error
+		entryState = client.connection.state
+	)
 
 	// Now dial the server
 	d := &net.Dialer{KeepAlive: client.keepAlivePeriod}
-	tlsConn, dialErr := tls.DialWithDialer(d, "tcp", client.connection.hostPortStr, client.connection.tlsConfig)
-	if dialErr != nil {
-		err = fmt.Errorf("tls.Dial() failed: %v", dialErr)
-		return
+	if client.connection.unixSocketPath != "" {
+		conn, dialErr = d.Dial("unix", client.connection.unixSocketPath)
+		if dialErr != nil {
+			err = fmt.Errorf("net.Dial() failed: %v", dialErr)
+			return
+		}
+	} else {
+		client.connection.tlsConfig = &tls.Config{
+			RootCAs: client.connection.x509CertPool,
+		}
+
+		conn, dialErr = tls.DialWithDialer(d, "tcp", client.connection.hostPortStr, client.connection.tlsConfig)
+		if dialErr != nil {
+			err = fmt.Errorf("tls.Dial() failed: %v", dialErr)
+			return
+		}
 	}
 
-	if client.connection.tlsConn != nil {
-		client.connection.tlsConn.Close()
-		client.connection.tlsConn = nil
+	if client.connection.conn != nil {
+		client.connection.conn.Close()
+		client.connection.conn = nil
 	}
 
-	client.connection.tlsConn = tlsConn
+	client.connection.conn = conn
 	client.connection.state = CONNECTED
 	client.connection.genNum++
 
 	// Send myUniqueID to server.   If this fails the dial will
 	// be retried.
-	err = client.sendMyInfo(tlsConn)
+	err = client.sendMyInfo(conn)
 	if err != nil {
-		_ = client.connection.tlsConn.Close()
-		client.connection.tlsConn = nil
+		_ = client.connection.conn.Close()
+		client.connection.conn = nil
 		client.connection.state = entryState
 		return
 	}
 
 	// Start readResponse goroutine to read responses from server
 	client.goroutineWG.Add(1)
-	go client.readReplies(client.connection.genNum, tlsConn)
+	go client.readReplies(client.connection.genNum, conn)
 
 	return
 }
@@ -0,0 +1,47 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package retryrpc
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// getPeerCreds returns the SO_PEERCRED credentials of a unix domain socket conn.
+func getPeerCreds(conn net.Conn) (peerCreds *PeerCreds, err error) {
+	var (
+		ucred    *unix.Ucred
+		ucredErr error
+		unixConn *net.UnixConn
+		ok       bool
+	)
+
+	unixConn, ok = conn.(*net.UnixConn)
+	if !ok {
+		err = fmt.Errorf("getPeerCreds() called on non-unix domain socket conn %v", conn.LocalAddr())
+		return
+	}
+
+	rawConn, err := unixConn.SyscallConn()
+	if err != nil {
+		return
+	}
+
+	err = rawConn.Control(func(fd uintptr) {
+		ucred, ucredErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return
+	}
+	if ucredErr != nil {
+		err = fmt.Errorf("getsockopt(SO_PEERCRED) failed: %v", ucredErr)
+		return
+	}
+
+	peerCreds = &PeerCreds{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}
+
+	return
+}
@@ -0,0 +1,16 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+// +build !linux
+
+package retryrpc
+
+import (
+	"net"
+)
+
+// getPeerCreds returns nil as SO_PEERCRED is not supported on this platform.
+func getPeerCreds(conn net.Conn) (peerCreds *PeerCreds, err error) {
+	return
+}
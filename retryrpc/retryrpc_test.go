@@ -44,13 +44,21 @@ func (m *MyType) unexportedFunction(i int) {
 	m.field1 = i
 }
 
+// testUnixSocketPath, if non-empty, has getNewServer() return a Server
+// listening on this unix domain socket rather than on 127.0.0.1:24456
+var testUnixSocketPath string
+
 func getNewServer(lt time.Duration, dontStartTrimmers bool) (rrSvr *Server, ip string, p int) {
 	var (
 		ipaddr = "127.0.0.1"
 		port   = 24456
 	)
-	config := &ServerConfig{LongTrim: lt, ShortTrim: 100 * time.Millisecond, IPAddr: "127.0.0.1",
-		Port: 24456, DeadlineIO: 5 * time.Second, dontStartTrimmers: dontStartTrimmers}
+	if testUnixSocketPath != "" {
+		ipaddr = UnixSocketScheme + testUnixSocketPath
+		port = 0
+	}
+	config := &ServerConfig{LongTrim: lt, ShortTrim: 100 * time.Millisecond, IPAddr: ipaddr,
+		Port: port, DeadlineIO: 5 * time.Second, dontStartTrimmers: dontStartTrimmers}
 
 	// Create a new RetryRPC Server.  Completed request will live on
 	// completedRequests for 10 seconds.
@@ -60,6 +68,15 @@ func getNewServer(lt time.Duration, dontStartTrimmers bool) (rrSvr *Server, ip s
 	return
 }
 
+// getRootCAPEM returns the RootCAx509CertificatePEM a client of rrSvr must
+// specify (none for a unix domain socket)
+func getRootCAPEM(rrSvr *Server) (rootCAx509CertificatePEM []byte) {
+	if rrSvr.Creds != nil {
+		rootCAx509CertificatePEM = rrSvr.Creds.RootCAx509CertificatePEM
+	}
+	return
+}
+
 // Test basic Server creation and deletion
 func testServer(t *testing.T) {
 	assert := assert.New(t)
@@ -86,7 +103,7 @@ func testServer(t *testing.T) {
 	rrSvr.Run()
 
 	// Now - setup a client to send requests to the server
-	clientConfig := &ClientConfig{MyUniqueID: "client 1", IPAddr: ipaddr, Port: port, RootCAx509CertificatePEM: rrSvr.Creds.RootCAx509CertificatePEM,
+	clientConfig := &ClientConfig{MyUniqueID: "client 1", IPAddr: ipaddr, Port: port, RootCAx509CertificatePEM: getRootCAPEM(rrSvr),
 		Callbacks: nil, DeadlineIO: 5 * time.Second}
 	rrClnt, newErr := NewClient(clientConfig)
 	assert.NotNil(rrClnt)
@@ -130,7 +147,7 @@ func testBtree(t *testing.T) {
 	assert.NotNil(rrSvr)
 
 	// Setup a client - we only will be targeting the btree
-	clientConfig := &ClientConfig{MyUniqueID: "client 1", IPAddr: ipaddr, Port: port, RootCAx509CertificatePEM: rrSvr.Creds.RootCAx509CertificatePEM,
+	clientConfig := &ClientConfig{MyUniqueID: "client 1", IPAddr: ipaddr, Port: port, RootCAx509CertificatePEM: getRootCAPEM(rrSvr),
 		Callbacks: nil, DeadlineIO: 5 * time.Second}
 	client, newErr := NewClient(clientConfig)
 	assert.NotNil(client)
@@ -165,6 +182,9 @@ func testBtree(t *testing.T) {
 	client.setHighestConsecutive()
 	assert.Equal(int(1), client.bt.Len())
 	assert.Equal(requestID(11), client.highestConsecutive)
+
+	// Unregister the client's bucketstats so that its MyUniqueID may be reused
+	client.Close()
 }
 
 // Per pfsagent statistics
@@ -5,11 +5,15 @@ package retryrpc
 
 import (
 	"container/list"
+	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"reflect"
 	"sync"
 	"time"
@@ -40,7 +44,7 @@ func (server *Server) closeClient(myConn net.Conn, myElm *list.Element) {
 func (server *Server) run() {
 	defer server.goroutineWG.Done()
 	for {
-		conn, err := server.tlsListener.Accept()
+		conn, err := server.listener.Accept()
 		if err != nil {
 			if !server.halting {
 				logger.ErrorfWithError(err, "net.Accept failed for Retry RPC listener")
@@ -64,6 +68,14 @@ func (server *Server) run() {
 		// Those race conditions are resolved if we serialize the recovery.
 		cCtx := &connCtx{conn: conn}
 		cCtx.cond = sync.NewCond(&cCtx.Mutex)
+		if server.unixSocketPath != "" {
+			cCtx.peerCreds, err = getPeerCreds(conn)
+			if err != nil {
+				logger.Warnf("getPeerCreds() returned err: %v\n", err)
+				server.closeClient(conn, elm)
+				continue
+			}
+		}
 		ci, err := server.getClientIDAndWait(cCtx)
 		if err != nil {
 			// Socket already had an error - just loop back
@@ -93,6 +105,95 @@ func (server *Server) run() {
 	}
 }
 
+// listenTLS listens for TCP connections on ipaddr:port secured by TLS.
+func (server *Server) listenTLS() (err error) {
+	portStr := fmt.Sprintf("%d", server.port)
+	hostPortStr := net.JoinHostPort(server.ipaddr, portStr)
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{server.Creds.serverTLSCertificate},
+	}
+
+	listenConfig := &net.ListenConfig{KeepAlive: server.keepAlivePeriod}
+	server.netListener, err = listenConfig.Listen(context.Background(), "tcp", hostPortStr)
+	if nil != err {
+		err = fmt.Errorf("tls.Listen() failed: %v", err)
+		return
+	}
+
+	server.tlsListener = tls.NewListener(server.netListener, tlsConfig)
+	server.listener = server.tlsListener
+
+	return
+}
+
+// listenUnixSocket listens for connections on the unix domain socket at
+// unixSocketPath.
+//
+// A socket file left behind by a prior Server that is no longer listening
+// (e.g. one that crashed) is removed so that the path may be reused.  The
+// socket file is removed again by Close().
+//
+// The socket is bound inside a private (0700) directory next to unixSocketPath
+// and only renamed into place once given unixSocketPerm, so that it is never
+// reachable with the (umask derived) mode net.Listen() creates it with.  As
+// such, unixSocketPath must be a few bytes shorter than the platform limit on
+// socket paths.
+func (server *Server) listenUnixSocket() (err error) {
+	fileInfo, statErr := os.Lstat(server.unixSocketPath)
+	if statErr == nil {
+		if fileInfo.Mode()&os.ModeSocket == 0 {
+			err = fmt.Errorf("%s exists and is not a unix domain socket", server.unixSocketPath)
+			return
+		}
+		conn, dialErr := net.Dial("unix", server.unixSocketPath)
+		if dialErr == nil {
+			conn.Close()
+			err = fmt.Errorf("%s is in use by another listener", server.unixSocketPath)
+			return
+		}
+		err = os.Remove(server.unixSocketPath)
+		if nil != err {
+			return
+		}
+	}
+
+	privateDir, err := ioutil.TempDir(filepath.Dir(server.unixSocketPath), ".sock")
+	if nil != err {
+		return
+	}
+	defer os.RemoveAll(privateDir)
+
+	privateSocketPath := filepath.Join(privateDir, "s")
+
+	unixListener, err := net.ListenUnix("unix", &net.UnixAddr{Name: privateSocketPath, Net: "unix"})
+	if nil != err {
+		err = fmt.Errorf("net.ListenUnix() failed: %v", err)
+		return
+	}
+
+	// The bound path changes with the rename below so Close() removes
+	// unixSocketPath itself
+	unixListener.SetUnlinkOnClose(false)
+
+	err = os.Chmod(privateSocketPath, server.unixSocketPerm)
+	if nil != err {
+		unixListener.Close()
+		return
+	}
+
+	err = os.Rename(privateSocketPath, server.unixSocketPath)
+	if nil != err {
+		unixListener.Close()
+		return
+	}
+
+	server.netListener = unixListener
+	server.listener = server.netListener
+
+	return
+}
+
 // processRequest is given a request from the client.
 func (server *Server) processRequest(ci *clientInfo, myConnCtx *connCtx, buf []byte) {
 	defer server.goroutineWG.Done()
@@ -142,7 +243,7 @@ func (server *Server) processRequest(ci *clientInfo, myConnCtx *connCtx, buf []b
 		// be unmarshaled again to retrieve the parameters specific to
 		// the RPC.
 		startRPC := time.Now()
-		ior := server.callRPCAndFormatReply(buf, &jReq)
+		ior := server.callRPCAndFormatReply(buf, &jReq, myConnCtx.peerCreds)
 		ci.stats.RPCLenUsec.Add(uint64(time.Since(startRPC) / time.Microsecond))
 		ci.stats.RPCcompleted.Add(1)
 
@@ -309,7 +410,7 @@ func (server *Server) serviceClient(ci *clientInfo, cCtx *connCtx) {
 }
 
 // callRPCAndMarshal calls the RPC and returns results to requestor
-func (server *Server) callRPCAndFormatReply(buf []byte, jReq *jsonRequest) (ior *ioReply) {
+func (server *Server) callRPCAndFormatReply(buf []byte, jReq *jsonRequest, peerCreds *PeerCreds) (ior *ioReply) {
 	var (
 		err error
 	)
@@ -342,7 +443,12 @@ func (server *Server) callRPCAndFormatReply(buf []byte, jReq *jsonRequest) (ior
 
 		// Call the method
 		function := ma.methodPtr.Func
-		returnValues := function.Call([]reflect.Value{server.receiver, req, myReply})
+		var returnValues []reflect.Value
+		if ma.passPeerCreds {
+			returnValues = function.Call([]reflect.Value{server.receiver, reflect.ValueOf(peerCreds), req, myReply})
+		} else {
+			returnValues = function.Call([]reflect.Value{server.receiver, req, myReply})
+		}
 
 		// The return value for the method is an error.
 		errInter := returnValues[0].Interface()
@@ -63,7 +63,7 @@ func testLoop(t *testing.T) {
 	rrSvr.Run()
 
 	// Start up the agents
-	parallelAgentSenders(t, rrSvr, ipAddr, port, agentCount, "RpcPing", sendCount, rrSvr.Creds.RootCAx509CertificatePEM)
+	parallelAgentSenders(t, rrSvr, ipAddr, port, agentCount, "RpcPing", sendCount, getRootCAPEM(rrSvr))
 
 	rrSvr.Close()
 }
@@ -103,7 +103,7 @@ func testLoopClientAckTrim(t *testing.T) {
 	rrSvr.Run()
 
 	// Start up the agents
-	parallelAgentSenders(t, rrSvr, ipAddr, port, agentCount, "RpcPing", sendCount, rrSvr.Creds.RootCAx509CertificatePEM)
+	parallelAgentSenders(t, rrSvr, ipAddr, port, agentCount, "RpcPing", sendCount, getRootCAPEM(rrSvr))
 
 	// Now for both trimmers to run
 	tm := time.Now()
@@ -165,7 +165,7 @@ func testLoopTTLTrim(t *testing.T) {
 	rrSvr.Run()
 
 	// Start up the agents
-	parallelAgentSenders(t, rrSvr, ipAddr, port, agentCount, "RpcPing", sendCount, rrSvr.Creds.RootCAx509CertificatePEM)
+	parallelAgentSenders(t, rrSvr, ipAddr, port, agentCount, "RpcPing", sendCount, getRootCAPEM(rrSvr))
 
 	// Use the TTL trimmer to remove all messages after guaranteeing we are
 	// past time when they should be removed
@@ -215,7 +215,7 @@ func testSendLargeRPC(t *testing.T) {
 	rrSvr.Run()
 
 	// Start up the agents
-	parallelAgentSenders(t, rrSvr, ipAddr, port, agentCount, "RpcPingLarge", sendCount, rrSvr.Creds.RootCAx509CertificatePEM)
+	parallelAgentSenders(t, rrSvr, ipAddr, port, agentCount, "RpcPingLarge", sendCount, getRootCAPEM(rrSvr))
 
 	// Now for both trimmers to run
 	tm := time.Now()
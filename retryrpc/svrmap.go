@@ -11,6 +11,7 @@ import (
 )
 
 var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
+var typeOfPeerCreds = reflect.TypeOf((*PeerCreds)(nil))
 
 // Find all methods for the type which can be exported.
 // Build svrMap listing methods available as well as their
@@ -26,19 +27,24 @@ func (server *Server) buildSvrMap(typ reflect.Type) {
 		// - needs three ins: receiver, *args, *reply
 		// - reply has to be a pointer and must be exported
 		// - method can only return one value of type error
+		//
+		// Additionally, a *PeerCreds may precede *args.
 		if method.PkgPath != "" {
 			continue
 		}
 
-		if mtype.NumIn() != 3 {
+		argIn := 1
+		if mtype.NumIn() == 4 && mtype.In(1) == typeOfPeerCreds {
+			argIn = 2
+		} else if mtype.NumIn() != 3 {
 			continue
 		}
-		argType := mtype.In(1)
+		argType := mtype.In(argIn)
 		if !isExportedOrBuiltinType(argType) {
 			continue
 		}
 
-		replyType := mtype.In(2)
+		replyType := mtype.In(argIn + 1)
 		if replyType.Kind() != reflect.Ptr {
 			continue
 		}
@@ -58,7 +64,7 @@ func (server *Server) buildSvrMap(typ reflect.Type) {
 
 		// We save off the request type so we know how to unmarshal the request.
 		// We use the reply type to allocate the reply struct and marshal the response.
-		ma := methodArgs{methodPtr: &method, request: argType, reply: replyType}
+		ma := methodArgs{methodPtr: &method, request: argType, reply: replyType, passPeerCreds: argIn == 2}
 		server.svrMap[mname] = &ma
 	}
 }
@@ -0,0 +1,189 @@
+// Copyright (c) 2015-2021, NVIDIA CORPORATION.
+// SPDX-License-Identifier: Apache-2.0
+
+package retryrpc
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/proxyfs/retryrpc/rpctest"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run the retryrpc tests over a unix domain socket
+func TestUnixSocket(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "retryrpc_unixsocket_")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	testUnixSocketPath = filepath.Join(tempDir, "retryrpc.sock")
+	defer func() {
+		testUnixSocketPath = ""
+	}()
+
+	testServer(t)
+	testBtree(t)
+	testUpCall(t)
+	testLoop(t)
+	testLoopClientAckTrim(t)
+	testLoopTTLTrim(t)
+	testSendLargeRPC(t)
+
+	testUnixSocketConfig(t)
+	testUnixSocketPeerCreds(t)
+	testUnixSocketRestart(t)
+}
+
+type UnixSocketServer struct{}
+
+type PeerCredsRequest struct{}
+
+type PeerCredsReply struct {
+	HavePeerCreds bool
+	PID           int32
+	UID           uint32
+	GID           uint32
+}
+
+func (s *UnixSocketServer) RpcPeerCreds(peerCreds *PeerCreds, in *PeerCredsRequest, reply *PeerCredsReply) (err error) {
+	if peerCreds != nil {
+		reply.HavePeerCreds = true
+		reply.PID = peerCreds.PID
+		reply.UID = peerCreds.UID
+		reply.GID = peerCreds.GID
+	}
+	return
+}
+
+// Test that TLS options are rejected for, and socket permissions applied to,
+// a unix domain socket
+func testUnixSocketConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	ipaddr := UnixSocketScheme + testUnixSocketPath
+
+	_, newErr := NewClient(&ClientConfig{MyUniqueID: "client 1", IPAddr: ipaddr, RootCAx509CertificatePEM: []byte("PEM"),
+		DeadlineIO: 5 * time.Second})
+	assert.NotNil(newErr, "RootCAx509CertificatePEM should be rejected for a unix domain socket")
+
+	_, newErr = NewClient(&ClientConfig{MyUniqueID: "client 1", IPAddr: ipaddr, Port: 24456,
+		DeadlineIO: 5 * time.Second})
+	assert.NotNil(newErr, "Port should be rejected for a unix domain socket")
+
+	rrSvr := NewServer(&ServerConfig{LongTrim: 10 * time.Second, ShortTrim: 100 * time.Millisecond, IPAddr: ipaddr,
+		Port: 24456, DeadlineIO: 5 * time.Second})
+	assert.NotNil(rrSvr.Start(), "Port should be rejected for a unix domain socket")
+
+	_, statErr := os.Lstat(testUnixSocketPath)
+	assert.True(os.IsNotExist(statErr), "a rejected Start() should not create the socket file")
+
+	rrSvr = NewServer(&ServerConfig{LongTrim: 10 * time.Second, ShortTrim: 100 * time.Millisecond, IPAddr: ipaddr,
+		UnixSocketPerm: 0660, DeadlineIO: 5 * time.Second})
+	assert.Nil(rrSvr.Creds)
+	assert.Nil(rrSvr.Register(rpctest.NewServer()))
+	assert.Nil(rrSvr.Start())
+	rrSvr.Run()
+
+	fileInfo, statErr := os.Stat(testUnixSocketPath)
+	assert.Nil(statErr)
+	assert.Equal(os.FileMode(0660), fileInfo.Mode().Perm())
+
+	// The private directory the socket was bound in is not left behind
+	strays, globErr := filepath.Glob(filepath.Join(filepath.Dir(testUnixSocketPath), ".sock*"))
+	assert.Nil(globErr)
+	assert.Empty(strays)
+
+	// A second Server may not take over a socket that is in use
+	rrSvr2 := NewServer(&ServerConfig{LongTrim: 10 * time.Second, ShortTrim: 100 * time.Millisecond, IPAddr: ipaddr,
+		DeadlineIO: 5 * time.Second})
+	assert.NotNil(rrSvr2.Start())
+
+	rrSvr.Close()
+
+	_, statErr = os.Stat(testUnixSocketPath)
+	assert.True(os.IsNotExist(statErr), "Close() should remove the socket file")
+
+	// A socket file left behind by a crashed Server is replaced
+	unixListener, listenErr := net.ListenUnix("unix", &net.UnixAddr{Name: testUnixSocketPath, Net: "unix"})
+	assert.Nil(listenErr)
+	unixListener.SetUnlinkOnClose(false)
+	unixListener.Close()
+
+	rrSvr, _, _ = getNewServer(10*time.Second, false)
+	assert.Nil(rrSvr.Register(rpctest.NewServer()))
+	assert.Nil(rrSvr.Start())
+	rrSvr.Run()
+	rrSvr.Close()
+}
+
+// Test that handlers are passed the peer credentials of the client
+func testUnixSocketPeerCreds(t *testing.T) {
+	assert := assert.New(t)
+
+	rrSvr, ipaddr, port := getNewServer(10*time.Second, false)
+	assert.Nil(rrSvr.Register(&UnixSocketServer{}))
+	assert.Nil(rrSvr.Start())
+	rrSvr.Run()
+
+	rrClnt, newErr := NewClient(&ClientConfig{MyUniqueID: "client 1", IPAddr: ipaddr, Port: port,
+		DeadlineIO: 5 * time.Second})
+	assert.Nil(newErr)
+
+	peerCredsReply := &PeerCredsReply{}
+	sendErr := rrClnt.Send("RpcPeerCreds", &PeerCredsRequest{}, peerCredsReply)
+	assert.Nil(sendErr)
+
+	if runtime.GOOS == "linux" {
+		assert.True(peerCredsReply.HavePeerCreds)
+		assert.Equal(int32(os.Getpid()), peerCredsReply.PID)
+		assert.Equal(uint32(os.Getuid()), peerCredsReply.UID)
+		assert.Equal(uint32(os.Getgid()), peerCredsReply.GID)
+	}
+
+	rrClnt.Close()
+	rrSvr.Close()
+}
+
+// Test that a client reconnects to a Server restarted on the same socket path
+func testUnixSocketRestart(t *testing.T) {
+	assert := assert.New(t)
+
+	rrSvr, ipaddr, port := getNewServer(10*time.Second, false)
+	assert.Nil(rrSvr.Register(rpctest.NewServer()))
+	assert.Nil(rrSvr.Start())
+	rrSvr.Run()
+
+	rrClnt, newErr := NewClient(&ClientConfig{MyUniqueID: "client 1", IPAddr: ipaddr, Port: port,
+		DeadlineIO: 5 * time.Second})
+	assert.Nil(newErr)
+
+	pingRequest := &rpctest.PingReq{Message: "Ping Me!"}
+	pingReply := &rpctest.PingReply{}
+	sendErr := rrClnt.Send("RpcPing", pingRequest, pingReply)
+	assert.Nil(sendErr)
+	assert.Equal("pong 8 bytes", pingReply.Message)
+
+	rrSvr.Close()
+
+	rrSvr, _, _ = getNewServer(10*time.Second, false)
+	assert.Nil(rrSvr.Register(rpctest.NewServer()))
+	assert.Nil(rrSvr.Start())
+	rrSvr.Run()
+
+	pingReply = &rpctest.PingReply{}
+	sendErr = rrClnt.Send("RpcPing", pingRequest, pingReply)
+	assert.Nil(sendErr)
+	assert.Equal("pong 8 bytes", pingReply.Message)
+	assert.Equal(1, rrSvr.CompletedCnt())
+
+	rrClnt.Close()
+	rrSvr.Close()
+}
@@ -63,7 +63,7 @@ func testUpCall(t *testing.T) {
 	cb.cond = sync.NewCond(&cb.Mutex)
 
 	clientConfig := &ClientConfig{MyUniqueID: myUniqueClientID, IPAddr: ipaddr, Port: port,
-		RootCAx509CertificatePEM: rrSvr.Creds.RootCAx509CertificatePEM, Callbacks: cb,
+		RootCAx509CertificatePEM: getRootCAPEM(rrSvr), Callbacks: cb,
 		DeadlineIO: 5 * time.Second}
 	rrClnt, newErr := NewClient(clientConfig)
 	assert.NotNil(rrClnt)